@@ -2,24 +2,21 @@ package main
 
 import (
 	"context"
-	"embed"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/ahmethakanbesel/youtube-video-summary/internal/middleware"
-	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/frontends"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/server"
 	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
 )
 
-//go:embed dist/*
-var uiAssets embed.FS
-
 var (
 	version = "dev"
 	commit  = "none"
@@ -40,46 +37,260 @@ func init() {
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	apiKey := os.Getenv("YOUTUBE_API_KEY")
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	// Initialize packages
-	youtubeClient := youtube.NewClient(apiKey, true, logger)
-	repo := transcript.NewMemoryRepository(logger)
-	svc := transcript.NewService(youtubeClient, repo)
-	rtr := transcript.NewRouter(svc, uiAssets)
+	// Pace outbound YouTube requests across all clients
+	youtube.SharedRateLimiter.Configure(
+		envInt(logger, "YOUTUBE_REQUESTS_PER_MINUTE", 0),
+		envDuration(logger, "YOUTUBE_MIN_REQUEST_DELAY", 0),
+	)
+
+	clientOpts := []youtube.Option{
+		youtube.WithParseOptions(youtube.ParseOptions{
+			PreserveStyling:    os.Getenv("PRESERVE_CAPTION_STYLING") == "true",
+			PreserveLineBreaks: os.Getenv("PRESERVE_CAPTION_LINE_BREAKS") == "true",
+		}),
+	}
+	// Invidious and Piped instances become the "invidious" and "piped"
+	// caption sources
+	if instance := os.Getenv("INVIDIOUS_INSTANCE"); instance != "" {
+		registerSource(logger, "invidious", frontends.Invidious, instance)
+	}
+	if instance := os.Getenv("PIPED_INSTANCE"); instance != "" {
+		registerSource(logger, "piped", frontends.Piped, instance)
+	}
+
+	if order := os.Getenv("CAPTION_SOURCES"); order != "" {
+		clientOpts = append(clientOpts, youtube.WithSourceOrder(strings.Split(order, ",")...))
+	}
+	switch backend := os.Getenv("YOUTUBE_BACKEND"); backend {
+	case "", "innertube":
+	case "dataapi":
+		clientOpts = append(clientOpts, youtube.WithDataAPI())
+	default:
+		logger.Error("Unknown YOUTUBE_BACKEND", "backend", backend)
+		os.Exit(1)
+	}
+	if token := os.Getenv("YOUTUBE_PO_TOKEN"); token != "" {
+		visitorData := os.Getenv("YOUTUBE_VISITOR_DATA")
+		if visitorData == "" {
+			logger.Warn("YOUTUBE_PO_TOKEN is set without YOUTUBE_VISITOR_DATA; the token will not validate against new visitor sessions")
+		}
+		clientOpts = append(clientOpts, youtube.WithPOTokenProvider(youtube.StaticPOToken{Token: token, VisitorData: visitorData}))
+	}
+	if dir := os.Getenv("YOUTUBE_RECORD_DIR"); dir != "" {
+		clientOpts = append(clientOpts, youtube.WithRecording(dir))
+	}
+	if dir := os.Getenv("YOUTUBE_REPLAY_DIR"); dir != "" {
+		clientOpts = append(clientOpts, youtube.WithReplay(dir))
+	}
 
-	// Middleware
-	mw := middleware.NewMiddleware(logger)
-	handler := mw.Apply(rtr)
+	// Shadow mode compares an alternate caption source order against the primary
+	var shadowOpts []youtube.Option
+	if order := os.Getenv("SHADOW_CAPTION_SOURCES"); order != "" {
+		shadowOpts = []youtube.Option{youtube.WithSourceOrder(strings.Split(order, ",")...)}
+	}
 
-	// Server
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%s", port),
-		Handler: handler,
+	// Cached transcripts are encrypted with a base64 encoded AES key
+	var cacheKey []byte
+	if encoded := os.Getenv("CACHE_ENCRYPTION_KEY"); encoded != "" {
+		var err error
+		if cacheKey, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			logger.Error("Invalid CACHE_ENCRYPTION_KEY", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	go func() {
-		logger.Info("Starting server", "addr", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Server failed", "error", err)
+	// Captions of private and unlisted videos are downloaded as the account
+	// authorized through Google OAuth
+	var oauth *youtube.OAuth
+	if clientID := os.Getenv("YOUTUBE_OAUTH_CLIENT_ID"); clientID != "" {
+		var err error
+		oauth, err = youtube.NewOAuth(clientID, os.Getenv("YOUTUBE_OAUTH_CLIENT_SECRET"),
+			os.Getenv("YOUTUBE_OAUTH_REDIRECT_URL"), os.Getenv("YOUTUBE_OAUTH_TOKEN_FILE"))
+		if err != nil {
+			logger.Error("Invalid YouTube OAuth configuration", "error", err)
 			os.Exit(1)
 		}
-	}()
+	}
+	if oauth == nil && os.Getenv("YOUTUBE_BACKEND") == "dataapi" {
+		logger.Error("YOUTUBE_BACKEND=dataapi requires YOUTUBE_OAUTH_CLIENT_ID")
+		os.Exit(1)
+	}
+
+	// Serve the web UI unless disabled at build time or runtime
+	ui, err := uiFS()
+	if err != nil {
+		logger.Error("Failed to load UI assets", "error", err)
+		os.Exit(1)
+	}
+	if os.Getenv("DISABLE_UI") == "true" {
+		ui = nil
+	}
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
+	srv, err := server.New(server.Config{
+		Addr:                   fmt.Sprintf(":%s", port),
+		BasePath:               os.Getenv("BASE_PATH"),
+		YouTubeAPIKey:          os.Getenv("YOUTUBE_API_KEY"),
+		InsecureSkipVerify:     true,
+		ClientOptions:          clientOpts,
+		ShadowClientOptions:    shadowOpts,
+		OAuth:                  oauth,
+		ShadowSampleRate:       envFloat(logger, "SHADOW_SAMPLE_RATE", 1),
+		CacheTTL:               envDuration(logger, "CACHE_TTL", 0),
+		StaleWhileRevalidate:   os.Getenv("CACHE_STALE_WHILE_REVALIDATE") == "true",
+		CompressCache:          os.Getenv("CACHE_COMPRESSION") == "true",
+		MaxCacheBytes:          int64(envInt(logger, "CACHE_MAX_BYTES", 0)),
+		CacheEncryptionKey:     cacheKey,
+		YtDlpArchiveDir:        os.Getenv("YTDLP_ARCHIVE_DIR"),
+		WatchDir:               os.Getenv("WATCH_DIR"),
+		WatchInterval:          envDuration(logger, "WATCH_INTERVAL", 0),
+		TimestampStyle:         os.Getenv("TIMESTAMP_STYLE"),
+		TimestampStyles:        envLanguages("TIMESTAMP_STYLE_"),
+		FeatureFlagsFile:       os.Getenv("FEATURE_FLAGS_FILE"),
+		SearchURL:              os.Getenv("SEARCH_URL"),
+		SearchIndex:            os.Getenv("SEARCH_INDEX"),
+		SearchAPIKey:           os.Getenv("SEARCH_API_KEY"),
+		VaultDir:               os.Getenv("VAULT_DIR"),
+		VaultTags:              envList("VAULT_TAGS"),
+		TrustedProxies:         envList("TRUSTED_PROXIES"),
+		AllowedIPs:             envList("IP_ALLOWLIST"),
+		CORSOrigins:            envList("CORS_ORIGINS"),
+		CORSMaxAge:             envDuration(logger, "CORS_MAX_AGE", server.DefaultCORSMaxAge),
+		DeniedIPs:              envList("IP_DENYLIST"),
+		ProofOfWorkDifficulty:  envInt(logger, "POW_DIFFICULTY", 0),
+		ProofOfWorkSecret:      os.Getenv("POW_SECRET"),
+		ProofOfWorkRoutes:      envList("POW_ROUTES"),
+		AnalyticsFile:          os.Getenv("ANALYTICS_FILE"),
+		PublicPopular:          os.Getenv("PUBLIC_POPULAR_VIDEOS") == "true",
+		MCP:                    os.Getenv("MCP_ENABLED") == "true",
+		ToolsAPI:               os.Getenv("TOOLS_API_ENABLED") == "true",
+		Version:                version,
+		Commit:                 commit,
+		BuildDate:              date,
+		AdminToken:             os.Getenv("ADMIN_TOKEN"),
+		UI:                     ui,
+		MaxConcurrentRequests:  envInt(logger, "MAX_CONCURRENT_REQUESTS", 0),
+		RouteConcurrencyLimits: envRouteLimits(logger, "MAX_CONCURRENT_REQUESTS_PER_ROUTE"),
+		Logger:                 logger,
+	})
+	if err != nil {
+		logger.Error("Failed to create server", "error", err)
+		os.Exit(1)
+	}
 
-	logger.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Error("Server shutdown failed", "error", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := srv.Run(ctx); err != nil {
+		logger.Error("Server failed", "error", err)
 		os.Exit(1)
 	}
-	logger.Info("Server stopped")
+}
+
+// registerSource registers the caption source for an instance URL, exiting
+// when the URL is invalid.
+func registerSource(logger *slog.Logger, name string, factory func(string) (youtube.SourceFactory, error), instance string) {
+	source, err := factory(instance)
+	if err != nil {
+		logger.Error("Invalid caption source instance", "source", name, "error", err)
+		os.Exit(1)
+	}
+	youtube.RegisterSource(name, source)
+}
+
+// envList reads a comma separated environment variable, nil when unset
+func envList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// envLanguages collects the environment variables named prefix plus a
+// language code, e.g. TIMESTAMP_STYLE_PT_BR, keyed by the code ("pt-BR").
+func envLanguages(prefix string) map[string]string {
+	values := make(map[string]string)
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		lang, ok := strings.CutPrefix(key, prefix)
+		if !ok || lang == "" || value == "" {
+			continue
+		}
+		base, region, _ := strings.Cut(lang, "_")
+		lang = strings.ToLower(base)
+		if region != "" {
+			lang += "-" + region
+		}
+		values[lang] = value
+	}
+	return values
+}
+
+// envInt reads an integer environment variable, falling back to def when it is
+// unset or malformed.
+func envInt(logger *slog.Logger, key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn("Ignoring invalid environment variable", "key", key, "value", value, "error", err)
+		return def
+	}
+	return n
+}
+
+// envFloat reads a float environment variable, falling back to def when it is
+// unset or malformed.
+func envFloat(logger *slog.Logger, key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logger.Warn("Ignoring invalid environment variable", "key", key, "value", value, "error", err)
+		return def
+	}
+	return f
+}
+
+// envDuration reads a time.Duration environment variable such as "500ms",
+// falling back to def when it is unset or malformed.
+func envDuration(logger *slog.Logger, key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Warn("Ignoring invalid environment variable", "key", key, "value", value, "error", err)
+		return def
+	}
+	return d
+}
+
+// envRouteLimits reads a comma separated list of path=limit pairs such as
+// "/api/v1/transcripts=8,/api/v1/transcripts/upload=2".
+func envRouteLimits(logger *slog.Logger, key string) map[string]int {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		route, value, _ := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			logger.Warn("Ignoring invalid route limit", "key", key, "value", pair, "error", err)
+			continue
+		}
+		limits[strings.TrimSpace(route)] = n
+	}
+	return limits
 }
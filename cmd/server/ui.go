@@ -0,0 +1,16 @@
+//go:build !noui
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed dist/*
+var uiAssets embed.FS
+
+// uiFS returns the embedded web UI build
+func uiFS() (fs.FS, error) {
+	return fs.Sub(uiAssets, "dist")
+}
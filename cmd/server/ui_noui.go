@@ -0,0 +1,10 @@
+//go:build noui
+
+package main
+
+import "io/fs"
+
+// uiFS returns nil in API-only builds, which serve API info at "/"
+func uiFS() (fs.FS, error) {
+	return nil, nil
+}
@@ -0,0 +1,124 @@
+// Package flags switches experimental features per deployment. Flags are
+// read from a JSON file of names and booleans, e.g. {"json3": false}, that
+// is reloaded when it changes.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often the flag file is checked for changes
+const DefaultReloadInterval = 10 * time.Second
+
+// Flags holds the switches of a flag file. A nil *Flags leaves every feature
+// at its default.
+type Flags struct {
+	path   string
+	logger *slog.Logger
+
+	mu      sync.RWMutex
+	values  map[string]bool
+	modTime time.Time
+	size    int64
+}
+
+// Load reads the flag file at path.
+func Load(path string, logger *slog.Logger) (*Flags, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	f := &Flags{path: path, logger: logger}
+	if _, err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Enabled reports whether the feature name is switched on, def when the file
+// does not set it.
+func (f *Flags) Enabled(name string, def bool) bool {
+	if f == nil {
+		return def
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if on, ok := f.values[name]; ok {
+		return on
+	}
+	return def
+}
+
+// Values returns a copy of the flags set in the file.
+func (f *Flags) Values() map[string]bool {
+	if f == nil {
+		return map[string]bool{}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return maps.Clone(f.values)
+}
+
+// Watch reloads the flag file whenever its size or modification time changes
+// until ctx is done. A file that fails to parse is logged once and the
+// previous flags stay in effect.
+func (f *Flags) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := f.reload()
+		if err != nil {
+			f.logger.Warn("Failed to reload feature flags", "file", f.path, "error", err)
+			continue
+		}
+		if changed {
+			f.logger.Info("Reloaded feature flags", "file", f.path, "flags", f.Values())
+		}
+	}
+}
+
+// reload reads the file if it changed since the last read.
+func (f *Flags) reload() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("stat feature flags: %w", err)
+	}
+	f.mu.RLock()
+	unchanged := f.values != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size
+	f.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("read feature flags: %w", err)
+	}
+	values := make(map[string]bool)
+	err = json.Unmarshal(data, &values)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// A broken file is reported once rather than on every check
+	f.modTime, f.size = info.ModTime(), info.Size()
+	if err != nil {
+		return false, fmt.Errorf("parse feature flags: %w", err)
+	}
+	f.values = values
+	return true, nil
+}
@@ -0,0 +1,285 @@
+// Package i18n translates API messages into the language requested by the
+// client's Accept-Language header.
+package i18n
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key identifies a translatable message
+type Key string
+
+// Messages used by the API
+const (
+	MethodNotAllowed  Key = "method_not_allowed"
+	NotFound          Key = "not_found"
+	InternalError     Key = "internal_error"
+	InvalidParameters Key = "invalid_parameters"
+	InvalidVideoURL   Key = "invalid_video_url"
+	InvalidInterval   Key = "invalid_interval"
+	NoTranscript      Key = "no_transcript"
+	EncodeFailed      Key = "encode_failed"
+	InvalidUpload     Key = "invalid_upload"
+	EmptySubtitleFile Key = "empty_subtitle_file"
+	NoCaptionTrack    Key = "no_caption_track"
+	NotSupported      Key = "not_supported"
+	// UploadBindingForbidden is returned for uploads naming a video ID
+	// without the admin token
+	UploadBindingForbidden Key = "upload_binding_forbidden"
+	// IdempotencyKeyReused is returned when an Idempotency-Key is sent again
+	// with a different request
+	IdempotencyKeyReused Key = "idempotency_key_reused"
+
+	// Rejections by the middleware
+	AccessDenied      Key = "access_denied"
+	ServerBusy        Key = "server_busy"
+	ChallengeRequired Key = "challenge_required"
+	ChallengeFailed   Key = "challenge_failed"
+
+	// Responses of the admin and OAuth endpoints
+	Unauthorized        Key = "unauthorized"
+	AnalyticsDisabled   Key = "analytics_disabled"
+	SearchDisabled      Key = "search_disabled"
+	BackfillFailed      Key = "backfill_failed"
+	OAuthStateExpired   Key = "oauth_state_expired"
+	OAuthDenied         Key = "oauth_denied"
+	OAuthExchangeFailed Key = "oauth_exchange_failed"
+)
+
+// Messages for a single invalid request field. They are format strings for Tf.
+const (
+	FieldRequired             Key = "field_required"
+	VideoSourceRequired       Key = "video_source_required"
+	InvalidVideoID            Key = "invalid_video_id"
+	NotANumber                Key = "not_a_number"
+	NotAWholeNumber           Key = "not_a_whole_number"
+	WholeNumber               Key = "whole_number"
+	OutOfRange                Key = "out_of_range"
+	SecondsOutOfRange         Key = "seconds_out_of_range"
+	NotBoolean                Key = "not_boolean"
+	OneOf                     Key = "one_of"
+	Either                    Key = "either"
+	Requires                  Key = "requires"
+	CannotCombine             Key = "cannot_combine"
+	MustBeAfter               Key = "must_be_after"
+	TooLong                   Key = "too_long"
+	UnsupportedFormat         Key = "unsupported_format"
+	UnsupportedSubtitleFormat Key = "unsupported_subtitle_format"
+	UnsupportedModel          Key = "unsupported_model"
+	InvalidRegion             Key = "invalid_region"
+	InvalidLanguage           Key = "invalid_language"
+	NotALanguage              Key = "not_a_language"
+	TooManyLanguages          Key = "too_many_languages"
+	InvalidTimeOffset         Key = "invalid_time_offset"
+	InvalidWindow             Key = "invalid_window"
+	InvalidTimestampStyle     Key = "invalid_timestamp_style"
+	UnknownCleanOption        Key = "unknown_clean_option"
+	SubtitleExportOnly        Key = "subtitle_export_only"
+	JSONLExportOnly           Key = "jsonl_export_only"
+	InterleaveFormats         Key = "interleave_formats"
+	InterleaveLanguages       Key = "interleave_languages"
+	NotWithInterleave         Key = "not_with_interleave"
+	Unreadable                Key = "unreadable"
+	IDMismatch                Key = "id_mismatch"
+	PositiveDuration          Key = "positive_duration"
+	InvalidIP                 Key = "invalid_ip"
+)
+
+// DefaultLanguage is used when no requested language is supported
+const DefaultLanguage = "en"
+
+var catalogs = map[string]map[Key]string{
+	"en": {
+		MethodNotAllowed:       "Method not allowed",
+		NotFound:               "Not found",
+		InternalError:          "Internal server error",
+		InvalidParameters:      "Invalid request parameters",
+		InvalidVideoURL:        "Invalid YouTube video URL",
+		InvalidInterval:        "Invalid interval",
+		NoTranscript:           "No transcript available",
+		EncodeFailed:           "Failed to encode response",
+		InvalidUpload:          "Invalid multipart upload",
+		EmptySubtitleFile:      "Subtitle file contains no cues",
+		NoCaptionTrack:         "No caption track in this language",
+		NotSupported:           "Not supported by the transcript source",
+		UploadBindingForbidden: "Storing an upload under a video ID requires the admin token",
+		IdempotencyKeyReused:   "The Idempotency-Key was already used for a different request",
+
+		AccessDenied:      "Access denied",
+		ServerBusy:        "Too many requests in progress, retry in %d seconds",
+		ChallengeRequired: "Solve a challenge from %s and send it in the %s header",
+		ChallengeFailed:   "Failed to issue challenge",
+
+		Unauthorized:        "Authentication required",
+		AnalyticsDisabled:   "Analytics are disabled, set ANALYTICS_FILE to enable them",
+		SearchDisabled:      "Search indexing is disabled, set SEARCH_URL to enable it",
+		BackfillFailed:      "Failed to index some transcripts",
+		OAuthStateExpired:   "Unknown or expired authorization, start again at %s",
+		OAuthDenied:         "Authorization was not granted: %s",
+		OAuthExchangeFailed: "Failed to exchange the authorization code",
+
+		FieldRequired:             "is required",
+		VideoSourceRequired:       "videoUrl or videoId is required",
+		InvalidVideoID:            "must be 1-64 letters, digits, '-' or '_'",
+		NotANumber:                "%q is not a number",
+		NotAWholeNumber:           "%q is not a whole number",
+		WholeNumber:               "must be a whole number",
+		OutOfRange:                "must be between %v and %v",
+		SecondsOutOfRange:         "must be between %v and %v seconds",
+		NotBoolean:                "must be true or false",
+		OneOf:                     "must be one of %s",
+		Either:                    "must be %s or %s",
+		Requires:                  "requires %s",
+		CannotCombine:             "cannot be combined with %s",
+		MustBeAfter:               "must be after %s",
+		TooLong:                   "must be at most %d characters",
+		UnsupportedFormat:         "unsupported format %q",
+		UnsupportedSubtitleFormat: "unsupported subtitle format %q",
+		UnsupportedModel:          "unsupported model %q, expected one of %s",
+		InvalidRegion:             "must be a two letter region code",
+		InvalidLanguage:           "must be a language code such as en or pt-BR",
+		NotALanguage:              "%q is not a language code such as en or pt-BR",
+		TooManyLanguages:          "at most %d languages are allowed",
+		InvalidTimeOffset:         "%q is not a time such as 90, 1m30s or 1:30",
+		InvalidWindow:             "must be a duration such as 24h, at most %s",
+		InvalidTimestampStyle:     "is not a valid timestamp style: %v",
+		UnknownCleanOption:        "unknown option %q, expected true, tags or fillers",
+		SubtitleExportOnly:        "is only supported for srt and vtt export",
+		JSONLExportOnly:           "is only supported for jsonl export",
+		InterleaveFormats:         "interleaved export is only supported for %s",
+		InterleaveLanguages:       "interleaved export needs exactly two languages",
+		NotWithInterleave:         "cannot be combined with interleaved export",
+		Unreadable:                "could not be read: %v",
+		IDMismatch:                "does not match the id in info",
+		PositiveDuration:          "must be a positive duration such as 24h",
+		InvalidIP:                 "must be an IPv4 or IPv6 address",
+	},
+	"tr": {
+		MethodNotAllowed:       "Bu yönteme izin verilmiyor",
+		NotFound:               "Bulunamadı",
+		InternalError:          "Sunucu hatası",
+		InvalidParameters:      "Geçersiz istek parametreleri",
+		InvalidVideoURL:        "Geçersiz YouTube video bağlantısı",
+		InvalidInterval:        "Geçersiz aralık",
+		NoTranscript:           "Altyazı metni bulunamadı",
+		EncodeFailed:           "Yanıt oluşturulamadı",
+		InvalidUpload:          "Geçersiz çok parçalı yükleme",
+		EmptySubtitleFile:      "Altyazı dosyası hiç satır içermiyor",
+		NoCaptionTrack:         "Bu dilde altyazı bulunamadı",
+		NotSupported:           "Altyazı kaynağı bunu desteklemiyor",
+		UploadBindingForbidden: "Yüklemeyi bir video kimliğiyle kaydetmek yönetici anahtarı gerektirir",
+		IdempotencyKeyReused:   "Idempotency-Key başka bir istek için zaten kullanıldı",
+
+		AccessDenied:      "Erişim reddedildi",
+		ServerBusy:        "Çok fazla istek işleniyor, %d saniye sonra tekrar deneyin",
+		ChallengeRequired: "%s adresinden bir görev alıp çözün ve çözümü %s başlığında gönderin",
+		ChallengeFailed:   "Görev oluşturulamadı",
+
+		Unauthorized:        "Kimlik doğrulaması gerekli",
+		AnalyticsDisabled:   "Analiz kapalı, açmak için ANALYTICS_FILE değişkenini ayarlayın",
+		SearchDisabled:      "Arama dizini kapalı, açmak için SEARCH_URL değişkenini ayarlayın",
+		BackfillFailed:      "Bazı altyazı metinleri dizine eklenemedi",
+		OAuthStateExpired:   "Bilinmeyen veya süresi dolmuş yetkilendirme, %s adresinden yeniden başlayın",
+		OAuthDenied:         "Yetki verilmedi: %s",
+		OAuthExchangeFailed: "Yetkilendirme kodu alınamadı",
+
+		FieldRequired:             "zorunludur",
+		VideoSourceRequired:       "videoUrl veya videoId zorunludur",
+		InvalidVideoID:            "1-64 harf, rakam, '-' veya '_' olmalıdır",
+		NotANumber:                "%q bir sayı değil",
+		NotAWholeNumber:           "%q bir tam sayı değil",
+		WholeNumber:               "tam sayı olmalıdır",
+		OutOfRange:                "%v ile %v arasında olmalıdır",
+		SecondsOutOfRange:         "%v ile %v saniye arasında olmalıdır",
+		NotBoolean:                "true veya false olmalıdır",
+		OneOf:                     "şunlardan biri olmalıdır: %s",
+		Either:                    "%s veya %s olmalıdır",
+		Requires:                  "%s gerektirir",
+		CannotCombine:             "%s ile birlikte kullanılamaz",
+		MustBeAfter:               "%s değerinden sonra olmalıdır",
+		TooLong:                   "en fazla %d karakter olmalıdır",
+		UnsupportedFormat:         "desteklenmeyen biçim %q",
+		UnsupportedSubtitleFormat: "desteklenmeyen altyazı biçimi %q",
+		UnsupportedModel:          "desteklenmeyen model %q, beklenenler: %s",
+		InvalidRegion:             "iki harfli bir bölge kodu olmalıdır",
+		InvalidLanguage:           "en veya pt-BR gibi bir dil kodu olmalıdır",
+		NotALanguage:              "%q, en veya pt-BR gibi bir dil kodu değil",
+		TooManyLanguages:          "en fazla %d dile izin verilir",
+		InvalidTimeOffset:         "%q, 90, 1m30s veya 1:30 gibi bir zaman değil",
+		InvalidWindow:             "24h gibi bir süre olmalıdır, en fazla %s",
+		InvalidTimestampStyle:     "geçerli bir zaman damgası biçimi değil: %v",
+		UnknownCleanOption:        "bilinmeyen seçenek %q, beklenenler: true, tags veya fillers",
+		SubtitleExportOnly:        "yalnızca srt ve vtt dışa aktarımında desteklenir",
+		JSONLExportOnly:           "yalnızca jsonl dışa aktarımında desteklenir",
+		InterleaveFormats:         "iç içe dışa aktarım yalnızca şu biçimlerde desteklenir: %s",
+		InterleaveLanguages:       "iç içe dışa aktarım tam olarak iki dil gerektirir",
+		NotWithInterleave:         "iç içe dışa aktarımla birlikte kullanılamaz",
+		Unreadable:                "okunamadı: %v",
+		IDMismatch:                "info içindeki kimlikle eşleşmiyor",
+		PositiveDuration:          "24h gibi pozitif bir süre olmalıdır",
+		InvalidIP:                 "bir IPv4 veya IPv6 adresi olmalıdır",
+	},
+}
+
+// Languages returns the supported language codes
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T returns the message for key in lang, falling back to English and then to
+// the key itself.
+func T(lang string, key Key) string {
+	if msg, ok := catalogs[lang][key]; ok {
+		return msg
+	}
+	if msg, ok := catalogs[DefaultLanguage][key]; ok {
+		return msg
+	}
+	return string(key)
+}
+
+// Tf formats the message for key in lang with args
+func Tf(lang string, key Key, args ...any) string {
+	if len(args) == 0 {
+		return T(lang, key)
+	}
+	return fmt.Sprintf(T(lang, key), args...)
+}
+
+// FromRequest picks the language for req from its Accept-Language header
+func FromRequest(req *http.Request) string {
+	return Negotiate(req.Header.Get("Accept-Language"))
+}
+
+// Negotiate returns the supported language with the highest weight in an
+// Accept-Language header such as "tr-TR,tr;q=0.9,en;q=0.8". Region subtags
+// match their base language. Without a match the default language is used.
+func Negotiate(header string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := catalogs[base]; ok && q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
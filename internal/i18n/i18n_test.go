@@ -0,0 +1,28 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var verbPattern = regexp.MustCompile(`%[a-zA-Z]`)
+
+// TestCatalogsComplete checks that every catalog translates every English
+// message with the same format verbs, so that Tf never prints %!(EXTRA ...).
+func TestCatalogsComplete(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, english := range catalogs[DefaultLanguage] {
+			msg, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing %s", lang, key)
+				continue
+			}
+			want := verbPattern.FindAllString(english, -1)
+			got := verbPattern.FindAllString(msg, -1)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: %s has verbs %v, want %v", lang, key, got, want)
+			}
+		}
+	}
+}
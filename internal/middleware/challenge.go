@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+)
+
+const (
+	// ChallengePath serves proof-of-work challenges when they are enabled
+	ChallengePath = "/api/v1/challenge"
+	// ChallengeHeader marks responses rejected for a missing solution
+	ChallengeHeader = "X-PoW-Challenge"
+	// SolutionHeader carries "<challenge>:<counter>" on protected requests
+	SolutionHeader = "X-PoW-Solution"
+	// challengeTTL is how long a challenge, and thus its solution, is valid
+	challengeTTL = 10 * time.Minute
+	// MaxChallengeDifficulty bounds the required leading zero bits so that
+	// a misconfiguration cannot lock out every client
+	MaxChallengeDifficulty = 32
+)
+
+// proofOfWork makes clients spend CPU time before calling expensive routes.
+// Challenges are signed with secret and bound to the client IP. Each one is
+// accepted for a single request before it expires, so that one solution does
+// not buy unlimited requests.
+type proofOfWork struct {
+	secret     []byte
+	difficulty int
+	routes     *routeSet
+
+	mu sync.Mutex
+	// used maps the challenges already spent to their expiry
+	used map[string]time.Time
+	// pruned is when expired challenges were last dropped from used
+	pruned time.Time
+}
+
+// Challenge is served at ChallengePath. Clients find a decimal counter such
+// that SHA-256 of "<challenge>:<counter>" starts with Difficulty zero bits and
+// send "<challenge>:<counter>" in the SolutionHeader.
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	Algorithm  string    `json:"algorithm"`
+	Header     string    `json:"header"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// SetProofOfWork requires a solved challenge of difficulty leading zero bits
+// on the given routes, patterns in the syntax of http.ServeMux. Challenges are
+// signed with secret, a random one when empty; instances behind a load
+// balancer must share it. Spent challenges are tracked per instance, so a
+// solution may be accepted once by each instance. A difficulty of zero or
+// less disables the check.
+func (m *Middleware) SetProofOfWork(difficulty int, secret string, routes []string) error {
+	if difficulty <= 0 {
+		m.pow = nil
+		return nil
+	}
+	if difficulty > MaxChallengeDifficulty {
+		return fmt.Errorf("proof-of-work difficulty %d exceeds %d", difficulty, MaxChallengeDifficulty)
+	}
+
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("failed to generate proof-of-work secret: %w", err)
+		}
+	}
+
+	set, err := newRouteSet(routes)
+	if err != nil {
+		return err
+	}
+	m.pow = &proofOfWork{secret: key, difficulty: difficulty, routes: set, used: make(map[string]time.Time)}
+	return nil
+}
+
+func (m *Middleware) requireProofOfWork(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.pow == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.URL.Path == ChallengePath {
+			m.serveChallenge(w, r)
+			return
+		}
+
+		if m.pow.routes.match(r) != "" && !m.pow.verify(r.Header.Get(SolutionHeader), ClientIP(r), time.Now()) {
+			m.logger.Warn("Proof of work missing or invalid", "method", r.Method, "path", r.URL.Path)
+			w.Header().Set(ChallengeHeader, ChallengePath)
+			writeError(w, r, http.StatusForbidden, i18n.ChallengeRequired, ChallengePath, SolutionHeader)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Middleware) serveChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, i18n.MethodNotAllowed)
+		return
+	}
+
+	expiresAt := time.Now().Add(challengeTTL).Truncate(time.Second)
+	challenge, err := m.pow.issue(ClientIP(r), expiresAt)
+	if err != nil {
+		m.logger.Error("Failed to issue challenge", "error", err)
+		writeError(w, r, http.StatusInternalServerError, i18n.ChallengeFailed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(Challenge{
+		Challenge:  challenge,
+		Difficulty: m.pow.difficulty,
+		Algorithm:  "sha256",
+		Header:     SolutionHeader,
+		ExpiresAt:  expiresAt,
+	})
+}
+
+// issue returns a challenge for ip valid until expiresAt, formatted as
+// "<payload>.<signature>" in unpadded base64url.
+func (p *proofOfWork) issue(ip string, expiresAt time.Time) (string, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload := strconv.FormatInt(expiresAt.Unix(), 10) + "|" + ip + "|" + base64.RawURLEncoding.EncodeToString(nonce)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(p.sign(encoded)), nil
+}
+
+// verify checks that solution solves a challenge issued to ip that has not
+// expired at now or been spent, spending it.
+func (p *proofOfWork) verify(solution, ip string, now time.Time) bool {
+	challenge, counter, ok := cutLast(solution, ":")
+	if !ok || counter == "" || len(counter) > 20 {
+		return false
+	}
+	if _, err := strconv.ParseUint(counter, 10, 64); err != nil {
+		return false
+	}
+
+	encoded, signature, ok := strings.Cut(challenge, ".")
+	if !ok {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, p.sign(encoded)) {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 3 || fields[1] != ip {
+		return false
+	}
+	expiry, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || now.Unix() > expiry {
+		return false
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(solution))) < p.difficulty {
+		return false
+	}
+	return p.spend(challenge, time.Unix(expiry, 0), now)
+}
+
+// spend marks challenge as used until expiresAt, reporting false when it
+// already was. Expired challenges are forgotten, since verify rejects them
+// anyway.
+func (p *proofOfWork) spend(challenge string, expiresAt, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.used[challenge]; ok {
+		return false
+	}
+	if now.Sub(p.pruned) >= time.Minute {
+		for spent, expiry := range p.used {
+			if now.After(expiry) {
+				delete(p.used, spent)
+			}
+		}
+		p.pruned = now
+	}
+	p.used[challenge] = expiresAt
+	return true
+}
+
+func (p *proofOfWork) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+)
+
+type clientIPKey struct{}
+
+// ipFilter resolves client IPs behind trusted proxies and applies the allow
+// and deny lists
+type ipFilter struct {
+	trusted []netip.Prefix
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+}
+
+// SetTrustedProxies makes X-Forwarded-For and X-Real-IP count as the client
+// IP when the request comes from one of the given IPs or CIDRs, e.g. a load
+// balancer. Without trusted proxies the headers are ignored.
+func (m *Middleware) SetTrustedProxies(proxies []string) error {
+	trusted, err := parsePrefixes(proxies)
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	m.ipFilter().trusted = trusted
+	return nil
+}
+
+// SetIPFilter rejects clients matching deny and, when allow is not empty,
+// clients not matching allow with 403. Entries are IPs or CIDRs.
+func (m *Middleware) SetIPFilter(allow, deny []string) error {
+	allowed, err := parsePrefixes(allow)
+	if err != nil {
+		return fmt.Errorf("invalid allowed IP: %w", err)
+	}
+	denied, err := parsePrefixes(deny)
+	if err != nil {
+		return fmt.Errorf("invalid denied IP: %w", err)
+	}
+	f := m.ipFilter()
+	f.allow, f.deny = allowed, denied
+	return nil
+}
+
+func (m *Middleware) ipFilter() *ipFilter {
+	if m.ips == nil {
+		m.ips = &ipFilter{}
+	}
+	return m.ips
+}
+
+// resolveClientIP stores the real client IP in the request context for
+// logging and per-client limits, and enforces the allow and deny lists.
+func (m *Middleware) resolveClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.ips == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := m.ips.clientAddr(r)
+		if !m.ips.permits(ip) {
+			m.logger.Warn("Client IP rejected", "client_ip", ip, "method", r.Method, "path", r.URL.Path)
+			writeError(w, r, http.StatusForbidden, i18n.AccessDenied)
+			return
+		}
+
+		if ip.IsValid() {
+			r = r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip.String()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientAddr walks X-Forwarded-For from the right, skipping trusted proxies,
+// as long as the peer itself is trusted. The first untrusted hop is the
+// client. X-Real-IP is used when a trusted peer sends no X-Forwarded-For.
+func (f *ipFilter) clientAddr(r *http.Request) netip.Addr {
+	ip := remoteAddr(r)
+	if !f.isTrusted(ip) {
+		return ip
+	}
+
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return realIP.Unmap()
+		}
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop cannot be trusted to have forwarded correctly
+			return ip
+		}
+		ip = hop.Unmap()
+		if !f.isTrusted(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func (f *ipFilter) isTrusted(ip netip.Addr) bool {
+	return ip.IsValid() && containsAddr(f.trusted, ip)
+}
+
+func (f *ipFilter) permits(ip netip.Addr) bool {
+	if len(f.allow) == 0 && len(f.deny) == 0 {
+		return true
+	}
+	if !ip.IsValid() || containsAddr(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || containsAddr(f.allow, ip)
+}
+
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefixes parses IPs and CIDRs, treating an IP as a single address
+// prefix. Blank entries are skipped.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, err
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func remoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap()
+}
+
+// ClientIP returns the IP address of the client that sent r, taking trusted
+// proxies into account.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	if ip := remoteAddr(r); ip.IsValid() {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
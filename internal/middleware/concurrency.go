@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+)
+
+// retryAfterSeconds is sent to clients rejected by the concurrency limiter
+const retryAfterSeconds = 1
+
+// concurrencyLimiter bounds in-flight requests with counting semaphores
+type concurrencyLimiter struct {
+	global chan struct{}
+	// perRoute maps the patterns of routes to their semaphores
+	perRoute map[string]chan struct{}
+	routes   *routeSet
+}
+
+// SetConcurrencyLimits bounds the number of in-flight requests globally and
+// per route, keyed by patterns in the syntax of http.ServeMux such as
+// "/api/v1/videos/{id}/html". Requests beyond a limit are rejected with 503
+// and a Retry-After header. A limit of zero or less disables it.
+func (m *Middleware) SetConcurrencyLimits(global int, perRoute map[string]int) error {
+	limiter := &concurrencyLimiter{perRoute: make(map[string]chan struct{})}
+	if global > 0 {
+		limiter.global = make(chan struct{}, global)
+	}
+	patterns := make([]string, 0, len(perRoute))
+	for route, limit := range perRoute {
+		route = strings.TrimSpace(route)
+		if limit > 0 {
+			limiter.perRoute[route] = make(chan struct{}, limit)
+			patterns = append(patterns, route)
+		}
+	}
+	routes, err := newRouteSet(patterns)
+	if err != nil {
+		return err
+	}
+	limiter.routes = routes
+	m.limiter = limiter
+	return nil
+}
+
+// SlotUsage is the number of in-flight requests against a concurrency limit
+type SlotUsage struct {
+	InFlight int `json:"inFlight"`
+	Limit    int `json:"limit"`
+}
+
+// ConcurrencyStats reports usage of the global limit, if any, and of each
+// route limit.
+func (m *Middleware) ConcurrencyStats() (global *SlotUsage, perRoute map[string]SlotUsage) {
+	if m.limiter == nil {
+		return nil, nil
+	}
+	if sem := m.limiter.global; sem != nil {
+		global = &SlotUsage{InFlight: len(sem), Limit: cap(sem)}
+	}
+	perRoute = make(map[string]SlotUsage, len(m.limiter.perRoute))
+	for route, sem := range m.limiter.perRoute {
+		perRoute[route] = SlotUsage{InFlight: len(sem), Limit: cap(sem)}
+	}
+	return global, perRoute
+}
+
+func (m *Middleware) limitConcurrency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := m.limiter.perRoute[m.limiter.routes.match(r)]
+		for _, sem := range []chan struct{}{route, m.limiter.global} {
+			if sem == nil {
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			default:
+				m.logger.Warn("Concurrency limit reached", "method", r.Method, "path", r.URL.Path)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+				writeError(w, r, http.StatusServiceUnavailable, i18n.ServerBusy, retryAfterSeconds)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,15 +1,29 @@
 package middleware
 
 import (
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
 )
 
 // Middleware provides HTTP middleware functions
 type Middleware struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	limiter *concurrencyLimiter
+	pow     *proofOfWork
+	ips     *ipFilter
+	// origins are allowed cross-origin requests, with preflights cached for
+	// corsMaxAge
+	origins    map[string]bool
+	corsMaxAge time.Duration
 }
 
 // NewMiddleware creates a new Middleware instance
@@ -19,20 +33,55 @@ func NewMiddleware(logger *slog.Logger) *Middleware {
 
 // Apply applies all middleware to the handler
 func (m *Middleware) Apply(next http.Handler) http.Handler {
-	// Chain middleware in order: CORS -> Logging -> Panic Recovery
-	return m.recoverPanic(m.logRequest(m.cors(next)))
+	// Chain middleware in order: Panic Recovery -> Client IP -> Logging -> CORS -> Proof of Work -> Concurrency Limits
+	return m.recoverPanic(m.resolveClientIP(m.logRequest(m.cors(m.requireProofOfWork(m.limitConcurrency(next))))))
+}
+
+// SetCORS allows cross-origin requests from exactly the given origins, such
+// as "https://example.com" or "chrome-extension://<id>", and lets browsers
+// cache preflight responses for maxAge. DISABLE_CORS=true allows any origin
+// instead.
+func (m *Middleware) SetCORS(origins []string, maxAge time.Duration) error {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		origin = strings.TrimSpace(origin)
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return fmt.Errorf("invalid CORS origin %q, expected scheme://host[:port]", origin)
+		}
+		allowed[origin] = true
+	}
+	m.origins = allowed
+	m.corsMaxAge = maxAge
+	return nil
 }
 
 func (m *Middleware) cors(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if os.Getenv("DISABLE_CORS") == "true" {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
+		origin := r.Header.Get("Origin")
+		allowOrigin := ""
+		switch {
+		case os.Getenv("DISABLE_CORS") == "true":
+			allowOrigin = "*"
+		case m.origins[origin]:
+			allowOrigin = origin
+		}
+		// Responses differ per origin when only some are allowed
+		if len(m.origins) > 0 && allowOrigin != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		if allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Prefer, Idempotency-Key, "+SolutionHeader)
+			w.Header().Set("Access-Control-Expose-Headers", "Location, Retry-After, "+ChallengeHeader)
 		}
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
+			if allowOrigin != "" && m.corsMaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(m.corsMaxAge.Seconds())))
+			}
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -46,7 +95,7 @@ func (m *Middleware) recoverPanic(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				m.logger.Error("Panic recovered", "error", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -58,6 +107,19 @@ func (m *Middleware) logRequest(next http.Handler) http.Handler {
 		start := time.Now()
 		next.ServeHTTP(w, r)
 		duration := time.Since(start)
-		m.logger.Info("Request completed", "method", r.Method, "path", r.URL.Path, "duration", duration)
+		m.logger.Info("Request completed", "method", r.Method, "path", r.URL.Path, "client_ip", ClientIP(r), "duration", duration)
+	})
+}
+
+// writeError responds with statusCode and the message key in the client's
+// language, in the JSON shape of the API's errors.
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, key i18n.Key, args ...any) {
+	lang := i18n.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":   http.StatusText(statusCode),
+		"message": i18n.Tf(lang, key, args...),
 	})
 }
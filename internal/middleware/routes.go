@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// routeSet matches requests against route patterns in the syntax of
+// http.ServeMux, such as "/api/v1/transcripts", "/api/v1/videos/{id}/html" or
+// "/api/v1/videos/" for every path below. The middleware runs before the
+// router, so the pattern the router matched is not known yet.
+type routeSet struct {
+	mux *http.ServeMux
+}
+
+// newRouteSet returns the set of patterns, an error for invalid or
+// conflicting ones.
+func newRouteSet(patterns []string) (set *routeSet, err error) {
+	set = &routeSet{mux: http.NewServeMux()}
+	seen := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+		if err := set.add(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// add registers pattern, turning the panic of http.ServeMux on invalid
+// patterns into an error.
+func (s *routeSet) add(pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid route pattern %q: %v", pattern, r)
+		}
+	}()
+	s.mux.Handle(pattern, http.NotFoundHandler())
+	return nil
+}
+
+// match returns the pattern matching r, "" for none.
+func (s *routeSet) match(r *http.Request) string {
+	if s == nil {
+		return ""
+	}
+	_, pattern := s.mux.Handler(r)
+	return pattern
+}
@@ -0,0 +1,211 @@
+// Package static serves the built web UI with cache headers, pre-compressed
+// variants, ETags and a fallback to index.html for client side routes.
+//
+// The UI is built with relative asset URLs, so a page loaded from a nested
+// path such as /videos/abc requests /videos/assets/index-1a2b.js. Those
+// requests are served from the build's assets/ directory.
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	indexFile = "index.html"
+	// hashedDir holds Vite's content hashed bundles, which never change
+	hashedDir = "assets/"
+
+	immutableCache = "public, max-age=31536000, immutable"
+	revalidate     = "no-cache"
+)
+
+// encodings lists pre-compressed variants in order of preference
+var encodings = []struct {
+	name, ext string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type file struct {
+	data []byte
+	etag string
+}
+
+// Handler serves files from a UI build
+type Handler struct {
+	fsys   fs.FS
+	routes []string
+	mu     sync.RWMutex
+	files  map[string]*file
+}
+
+// NewHandler serves the UI build in fsys. Page requests for routes, paths
+// such as "/watch" or prefixes such as "/videos/", get index.html so that the
+// UI can route them. Page requests for other unknown paths get index.html with
+// status 404, so that typos are not reported as found.
+func NewHandler(fsys fs.FS, routes ...string) *Handler {
+	return &Handler{
+		fsys:   fsys,
+		routes: routes,
+		files:  make(map[string]*file),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = indexFile
+	}
+
+	status := http.StatusOK
+	if _, err := h.load(name); err != nil {
+		if relative, ok := h.relativeFile(name); ok {
+			name = relative
+		} else if path.Ext(name) != "" || strings.HasPrefix(name, "api/") || !acceptsHTML(r) {
+			// Only page loads fall back to the UI; missing files and API
+			// paths get a plain 404
+			http.NotFound(w, r)
+			return
+		} else {
+			if !h.isRoute("/" + name) {
+				status = http.StatusNotFound
+			}
+			name = indexFile
+		}
+	}
+
+	if strings.HasPrefix(name, hashedDir) {
+		w.Header().Set("Cache-Control", immutableCache)
+	} else {
+		w.Header().Set("Cache-Control", revalidate)
+	}
+	w.Header().Set("Vary", "Accept-Encoding")
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	f, encoding := h.negotiate(name, r.Header.Get("Accept-Encoding"))
+	if f == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	if status != http.StatusOK {
+		w.Header().Set("Content-Length", strconv.Itoa(len(f.data)))
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			w.Write(f.data)
+		}
+		return
+	}
+	w.Header().Set("ETag", f.etag)
+
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(f.data))
+}
+
+// relativeFile resolves a request for a missing nested path made by a page
+// loaded from below the root, such as "videos/assets/index-1a2b.js" or
+// "videos/vite.svg", to the file of the build it refers to.
+func (h *Handler) relativeFile(name string) (string, bool) {
+	if path.Ext(name) == "" || !strings.Contains(name, "/") {
+		return "", false
+	}
+	candidate := path.Base(name)
+	if i := strings.LastIndex(name, "/"+hashedDir); i >= 0 {
+		candidate = name[i+1:]
+	}
+	if _, err := h.load(candidate); err != nil {
+		return "", false
+	}
+	return candidate, true
+}
+
+// isRoute reports whether urlPath is one of the UI's client side routes
+func (h *Handler) isRoute(urlPath string) bool {
+	for _, route := range h.routes {
+		if urlPath == route || (strings.HasSuffix(route, "/") && strings.HasPrefix(urlPath, route)) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsHTML reports whether r is a page load rather than a request for a
+// script, image or API
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// negotiate picks the best pre-compressed variant of name accepted by the
+// client, falling back to the uncompressed file.
+func (h *Handler) negotiate(name, acceptEncoding string) (*file, string) {
+	for _, enc := range encodings {
+		if !acceptsEncoding(acceptEncoding, enc.name) {
+			continue
+		}
+		if f, err := h.load(name + enc.ext); err == nil {
+			return f, enc.name
+		}
+	}
+
+	f, err := h.load(name)
+	if err != nil {
+		return nil, ""
+	}
+	return f, ""
+}
+
+// load reads name from the UI build once and caches it with its ETag
+func (h *Handler) load(name string) (*file, error) {
+	h.mu.RLock()
+	f, ok := h.files[name]
+	h.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+
+	data, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	f = &file{
+		data: data,
+		etag: `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+
+	h.mu.Lock()
+	h.files[name] = f
+	h.mu.Unlock()
+	return f, nil
+}
+
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		return strings.ReplaceAll(params, " ", "") != "q=0"
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+)
+
+// Function is a tool in the OpenAI function calling format, as listed in the
+// tools parameter of chat completion requests
+type Function struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+type FunctionDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// Functions lists Tools in the OpenAI function calling format.
+func Functions() []Function {
+	list := Tools()
+	functions := make([]Function, 0, len(list))
+	for _, tool := range list {
+		functions = append(functions, Function{
+			Type: "function",
+			Function: FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+	return functions
+}
+
+// FunctionCall is a tool call as returned by the model: either the bare
+// function with name and arguments or the whole tool_calls entry wrapping it.
+// Arguments may be the JSON encoded string models produce or an object.
+type FunctionCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+	Function  *struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ToolMessage is the answer to a tool call, ready to be appended to the
+// conversation as the tool's message
+type ToolMessage struct {
+	Role       string `json:"role"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Content is the JSON encoded result or, on failure, the error message
+	Content string `json:"content"`
+}
+
+// FunctionHandlers serve the function calling schema and dispatcher
+type FunctionHandlers struct {
+	svc    *transcript.Service
+	logger *slog.Logger
+}
+
+func NewFunctionHandlers(svc *transcript.Service, logger *slog.Logger) *FunctionHandlers {
+	return &FunctionHandlers{svc: svc, logger: logger}
+}
+
+// HandleSchema serves Functions.
+func (h *FunctionHandlers) HandleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+	h.writeJSON(w, Functions(), http.StatusOK)
+}
+
+// HandleCall runs a FunctionCall and responds with a ToolMessage. Failed
+// calls carry the error as content with a 4xx or 5xx status, so that it can
+// be passed back to the model all the same.
+func (h *FunctionHandlers) HandleCall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var call FunctionCall
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxMCPMessageBytes)).Decode(&call); err != nil {
+		h.writeJSON(w, ToolMessage{Role: "tool", Content: "invalid tool call: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	name, args := call.Name, call.Arguments
+	if call.Function != nil {
+		name, args = call.Function.Name, call.Function.Arguments
+	}
+	// Models send the arguments as a JSON encoded string
+	var encoded string
+	if err := json.Unmarshal(args, &encoded); err == nil {
+		args = json.RawMessage(encoded)
+	}
+
+	msg := ToolMessage{Role: "tool", ToolCallID: call.ID}
+	result, err := Call(r.Context(), h.svc, name, args)
+	if err != nil {
+		msg.Content = errorMessage(h.logger, name, err)
+		h.writeJSON(w, msg, callStatus(err))
+		return
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("Failed to encode tool result", "tool", name, "error", err)
+		msg.Content = "failed to encode result"
+		h.writeJSON(w, msg, http.StatusInternalServerError)
+		return
+	}
+	msg.Content = string(content)
+	h.writeJSON(w, msg, http.StatusOK)
+}
+
+// callStatus maps errors of Call to response status codes
+func callStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownTool), errors.Is(err, transcript.ErrNoTranscript):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidArguments), errors.Is(err, transcript.ErrInvalidURL):
+		return http.StatusBadRequest
+	case errors.Is(err, transcript.ErrNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func (h *FunctionHandlers) writeJSON(w http.ResponseWriter, body any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+)
+
+const (
+	// mcpServerName identifies the server to MCP clients
+	mcpServerName = "youtube-video-summary"
+	// maxMCPMessageBytes bounds a single JSON-RPC message
+	maxMCPMessageBytes = 1 << 20
+)
+
+// mcpProtocolVersions are the MCP revisions spoken, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpContent is a text block of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpCallResult struct {
+	Content           []mcpContent `json:"content"`
+	StructuredContent any          `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError"`
+}
+
+// MCPHandler serves the tools over the Model Context Protocol's Streamable
+// HTTP transport. Every message is POSTed and answered with a single JSON
+// response; the server never initiates messages, so no event stream is
+// offered and sessions are not tracked.
+type MCPHandler struct {
+	svc     *transcript.Service
+	version string
+	logger  *slog.Logger
+}
+
+// NewMCPHandler returns an MCP endpoint for svc reporting version to clients.
+func NewMCPHandler(svc *transcript.Service, version string, logger *slog.Logger) *MCPHandler {
+	if version == "" {
+		version = "dev"
+	}
+	return &MCPHandler{svc: svc, version: version, logger: logger}
+}
+
+func (h *MCPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMCPMessageBytes))
+	if err != nil {
+		h.writeResponse(w, rpcResponse{Error: &rpcError{Code: rpcParseError, Message: "request too large or unreadable"}})
+		return
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.writeResponse(w, rpcResponse{Error: &rpcError{Code: rpcParseError, Message: "parse error"}})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		h.writeResponse(w, rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}})
+		return
+	}
+
+	// Notifications, such as notifications/initialized, get no response
+	if req.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	result, rpcErr := h.handle(r, req)
+	h.writeResponse(w, rpcResponse{ID: req.ID, Result: result, Error: rpcErr})
+}
+
+// handle dispatches a request to its method.
+func (h *MCPHandler) handle(r *http.Request, req rpcRequest) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid params"}
+		}
+		// Clients on an unknown revision are offered the newest one
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": mcpServerName, "version": h.version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": Tools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid params"}
+		}
+		return h.call(r, params.Name, params.Arguments)
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// call runs a tool. Failures of the tool itself are reported in the result
+// so that the model sees them, unknown tools are protocol errors.
+func (h *MCPHandler) call(r *http.Request, name string, args json.RawMessage) (any, *rpcError) {
+	result, err := Call(r.Context(), h.svc, name, args)
+	if errors.Is(err, ErrUnknownTool) {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	if err != nil {
+		return mcpCallResult{Content: []mcpContent{{Type: "text", Text: errorMessage(h.logger, name, err)}}, IsError: true}, nil
+	}
+
+	text, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("Failed to encode tool result", "tool", name, "error", err)
+		return mcpCallResult{Content: []mcpContent{{Type: "text", Text: "failed to encode result"}}, IsError: true}, nil
+	}
+	return mcpCallResult{Content: []mcpContent{{Type: "text", Text: string(text)}}, StructuredContent: result}, nil
+}
+
+func (h *MCPHandler) writeResponse(w http.ResponseWriter, resp rpcResponse) {
+	resp.JSONRPC = "2.0"
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("Failed to encode MCP response", "error", err)
+	}
+}
@@ -0,0 +1,263 @@
+// Package tools exposes the transcript service as tools that LLM agents call
+// with JSON arguments, for the MCP endpoint and function calling backends.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+)
+
+// maxMatches bounds the segments returned by search_video
+const maxMatches = 50
+
+var (
+	// ErrUnknownTool is returned by Call for names not in Tools
+	ErrUnknownTool = errors.New("unknown tool")
+	// ErrInvalidArguments is returned by Call for arguments that do not match
+	// the tool's input schema
+	ErrInvalidArguments = errors.New("invalid arguments")
+)
+
+// Tool describes a callable tool with a JSON Schema of its arguments
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// videoSchema is shared by the tools that take a video
+const videoSchema = `"video": {"type": "string", "description": "YouTube video URL or 11 character video ID"}`
+
+// Tools lists the available tools in a stable order.
+func Tools() []Tool {
+	return []Tool{
+		{
+			Name: "get_transcript",
+			Description: "Fetch the transcript of a YouTube video as timestamped lines. " +
+				"Use from and to to read part of a long video.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    ` + videoSchema + `,
+    "lang": {"type": "string", "description": "Caption language code such as en or pt-BR, the video's default when omitted"},
+    "from": {"type": "string", "description": "Start time such as 90, 1m30s or 1:30"},
+    "to": {"type": "string", "description": "End time such as 90, 1m30s or 1:30"}
+  },
+  "required": ["video"],
+  "additionalProperties": false
+}`),
+		},
+		{
+			Name:        "search_video",
+			Description: "Find the moments of a YouTube video where a phrase is said. Returns the matching transcript lines with their start times.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    ` + videoSchema + `,
+    "query": {"type": "string", "description": "Words to look for, matched case-insensitively"},
+    "lang": {"type": "string", "description": "Caption language code such as en or pt-BR, the video's default when omitted"}
+  },
+  "required": ["video", "query"],
+  "additionalProperties": false
+}`),
+		},
+		{
+			Name:        "list_caption_languages",
+			Description: "List the caption languages available for a YouTube video, including automatic captions.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    ` + videoSchema + `
+  },
+  "required": ["video"],
+  "additionalProperties": false
+}`),
+		},
+	}
+}
+
+// Arguments of the tools, decoded strictly so that misspelled fields are
+// reported instead of ignored
+type (
+	transcriptArgs struct {
+		Video string `json:"video"`
+		Lang  string `json:"lang"`
+		From  string `json:"from"`
+		To    string `json:"to"`
+	}
+	searchArgs struct {
+		Video string `json:"video"`
+		Query string `json:"query"`
+		Lang  string `json:"lang"`
+	}
+	languageArgs struct {
+		Video string `json:"video"`
+	}
+)
+
+// TranscriptResult is returned by get_transcript
+type TranscriptResult struct {
+	VideoID  string `json:"videoId"`
+	Title    string `json:"title"`
+	Language string `json:"language,omitempty"`
+	// Text holds one "(mm:ss) text" line per interval
+	Text string `json:"text"`
+}
+
+// SearchResult is returned by search_video
+type SearchResult struct {
+	VideoID string  `json:"videoId"`
+	Title   string  `json:"title"`
+	Matches []Match `json:"matches"`
+	// Truncated is set when more than the returned matches were found
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Match is a transcript segment containing the searched words
+type Match struct {
+	Start     float64 `json:"start"`
+	Timestamp string  `json:"timestamp"`
+	Text      string  `json:"text"`
+}
+
+// Call runs the tool name with its JSON arguments. Errors wrap
+// ErrUnknownTool, ErrInvalidArguments or the service's errors.
+func Call(ctx context.Context, svc *transcript.Service, name string, args json.RawMessage) (any, error) {
+	switch name {
+	case "get_transcript":
+		var a transcriptArgs
+		if err := decodeArgs(args, &a); err != nil {
+			return nil, err
+		}
+		return getTranscript(ctx, svc, a)
+	case "search_video":
+		var a searchArgs
+		if err := decodeArgs(args, &a); err != nil {
+			return nil, err
+		}
+		return searchVideo(ctx, svc, a)
+	case "list_caption_languages":
+		var a languageArgs
+		if err := decodeArgs(args, &a); err != nil {
+			return nil, err
+		}
+		req, err := bind(svc, transcript.TranscriptQuery{}, a.Video)
+		if err != nil {
+			return nil, err
+		}
+		return svc.CaptionAvailability(ctx, req.VideoID)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownTool, name)
+	}
+}
+
+func decodeArgs(args json.RawMessage, v any) error {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(strings.NewReader(string(args)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArguments, err)
+	}
+	return nil
+}
+
+// bind validates video and the other query fields like the transcript
+// endpoint does, resolving URLs to their video ID.
+func bind(svc *transcript.Service, query transcript.TranscriptQuery, video string) (transcript.TranscriptRequest, error) {
+	if video == "" {
+		return transcript.TranscriptRequest{}, fmt.Errorf("%w: video is required", ErrInvalidArguments)
+	}
+	if svc.IsValidUrl(video) {
+		query.VideoID = svc.ExtractVideoId(video)
+		if query.VideoID == "" {
+			return transcript.TranscriptRequest{}, transcript.ErrInvalidURL
+		}
+	} else {
+		query.VideoID = video
+	}
+
+	req, err := query.Bind()
+	if err != nil {
+		return transcript.TranscriptRequest{}, fmt.Errorf("%w: %v", ErrInvalidArguments, err)
+	}
+	return req, nil
+}
+
+func getTranscript(ctx context.Context, svc *transcript.Service, a transcriptArgs) (TranscriptResult, error) {
+	req, err := bind(svc, transcript.TranscriptQuery{Lang: a.Lang, From: a.From, To: a.To}, a.Video)
+	if err != nil {
+		return TranscriptResult{}, err
+	}
+	resp, err := svc.GetTranscripts(ctx, req)
+	if err != nil {
+		return TranscriptResult{}, err
+	}
+	return TranscriptResult{
+		VideoID:  resp.VideoID,
+		Title:    resp.Title,
+		Language: resp.Language,
+		Text:     strings.Join(resp.Formatted, "\n"),
+	}, nil
+}
+
+func searchVideo(ctx context.Context, svc *transcript.Service, a searchArgs) (SearchResult, error) {
+	query := strings.ToLower(strings.Join(strings.Fields(a.Query), " "))
+	if query == "" {
+		return SearchResult{}, fmt.Errorf("%w: query is required", ErrInvalidArguments)
+	}
+	req, err := bind(svc, transcript.TranscriptQuery{Lang: a.Lang}, a.Video)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	resp, err := svc.GetTranscripts(ctx, req)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	result := SearchResult{VideoID: resp.VideoID, Title: resp.Title, Matches: []Match{}}
+	if resp.Raw == nil {
+		return result, nil
+	}
+	for _, segment := range resp.Raw.Segments {
+		text := strings.Join(strings.Fields(segment.Text), " ")
+		if !strings.Contains(strings.ToLower(text), query) {
+			continue
+		}
+		if len(result.Matches) == maxMatches {
+			result.Truncated = true
+			break
+		}
+		result.Matches = append(result.Matches, Match{
+			Start:     segment.StartTime,
+			Timestamp: format.Timestamp(segment.StartTime),
+			Text:      text,
+		})
+	}
+	return result, nil
+}
+
+// errorMessage describes a failure of Call in words a model can act on,
+// without exposing upstream details. Failures that are not the caller's
+// fault are logged.
+func errorMessage(logger *slog.Logger, name string, err error) string {
+	switch {
+	case errors.Is(err, ErrUnknownTool),
+		errors.Is(err, ErrInvalidArguments),
+		errors.Is(err, transcript.ErrInvalidURL),
+		errors.Is(err, transcript.ErrNoTranscript),
+		errors.Is(err, transcript.ErrNotSupported):
+		return err.Error()
+	default:
+		logger.Error("Tool call failed", "tool", name, "error", err)
+		return transcript.ErrFailedToGet.Error()
+	}
+}
@@ -0,0 +1,358 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/middleware"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+const (
+	// maxAnalyticsRecords bounds the request records kept in memory
+	maxAnalyticsRecords = 100_000
+	// maxTopVideos bounds the videos listed in an analytics report
+	maxTopVideos = 20
+)
+
+// Request outcomes recorded in the analytics log
+const (
+	OutcomeOK       = "ok"
+	OutcomeInvalid  = "invalid"
+	OutcomeNotFound = "not_found"
+	OutcomeError    = "error"
+)
+
+// RequestRecord is one processed transcript request, stored as a line of the
+// analytics log
+type RequestRecord struct {
+	Time     time.Time   `json:"time"`
+	VideoID  string      `json:"videoId,omitempty"`
+	Title    string      `json:"title,omitempty"`
+	Language string      `json:"language,omitempty"`
+	Outcome  string      `json:"outcome"`
+	Cache    CacheStatus `json:"cache,omitempty"`
+	// Error is the youtube.ClassifyError class of failed requests
+	Error string `json:"error,omitempty"`
+	// LatencyMs is the time spent serving the request
+	LatencyMs float64 `json:"latencyMs"`
+	// Client is the client network, the IP truncated to /24 or /48
+	Client string `json:"client,omitempty"`
+}
+
+// analytics keeps recent request records in memory and appends every record
+// to a JSON lines file, which is replayed on startup
+type analytics struct {
+	mu      sync.Mutex
+	file    *os.File
+	records []RequestRecord
+}
+
+// AnalyticsReport summarizes the requests of a trailing window
+type AnalyticsReport struct {
+	Window   string `json:"window"`
+	Requests int    `json:"requests"`
+	// Failures are requests that failed on the server or upstream side, as
+	// opposed to invalid requests and videos without captions
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failureRate"`
+	// Outcomes counts requests by outcome
+	Outcomes map[string]int `json:"outcomes"`
+	// Clients is the number of distinct client networks
+	Clients   int          `json:"clients"`
+	TopVideos []VideoCount `json:"topVideos"`
+	Traffic   []TrafficBin `json:"traffic"`
+}
+
+// VideoCount is how often a video was requested
+type VideoCount struct {
+	VideoID  string `json:"videoId"`
+	Title    string `json:"title,omitempty"`
+	Requests int    `json:"requests"`
+}
+
+// TrafficBin counts the requests starting at Start
+type TrafficBin struct {
+	Start    time.Time `json:"start"`
+	Requests int       `json:"requests"`
+	Failures int       `json:"failures"`
+}
+
+// SetAnalytics records every transcript request as a JSON line appended to
+// path, replaying the records already in the file. An empty path disables
+// recording.
+func (s *Service) SetAnalytics(path string) error {
+	if path == "" {
+		s.analytics = nil
+		return nil
+	}
+
+	a := &analytics{}
+	if err := a.replay(path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open analytics log: %w", err)
+	}
+	a.file = file
+	s.analytics = a
+	return nil
+}
+
+// replay loads the most recent records of the log at path, skipping lines
+// that cannot be decoded.
+func (a *analytics) replay(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open analytics log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record RequestRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		a.records = append(a.records, record)
+		if len(a.records) > 2*maxAnalyticsRecords {
+			a.records = slices.Clone(a.records[len(a.records)-maxAnalyticsRecords:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read analytics log: %w", err)
+	}
+	if len(a.records) > maxAnalyticsRecords {
+		a.records = a.records[len(a.records)-maxAnalyticsRecords:]
+	}
+	return nil
+}
+
+// add keeps record and appends it to the log. Write failures are returned
+// for logging only.
+func (a *analytics) add(record RequestRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.records = append(a.records, record)
+	if len(a.records) > 2*maxAnalyticsRecords {
+		a.records = slices.Clone(a.records[len(a.records)-maxAnalyticsRecords:])
+	}
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// since returns a copy of the records at or after t, oldest first.
+func (a *analytics) since(t time.Time) []RequestRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i, _ := slices.BinarySearchFunc(a.records, t, func(r RequestRecord, t time.Time) int {
+		return r.Time.Compare(t)
+	})
+	return slices.Clone(a.records[i:])
+}
+
+// remove drops the records matching match from memory and rewrites the log
+// without them, returning how many were removed.
+func (a *analytics) remove(match func(RequestRecord) bool) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	kept := a.records[:0]
+	for _, record := range a.records {
+		if !match(record) {
+			kept = append(kept, record)
+		}
+	}
+	removed := len(a.records) - len(kept)
+	clear(a.records[len(kept):])
+	a.records = kept
+	if removed == 0 {
+		return 0, nil
+	}
+
+	// Records beyond those kept in memory are dropped from the log as well,
+	// since they may match too
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range a.records {
+		if err := enc.Encode(record); err != nil {
+			return removed, err
+		}
+	}
+
+	path := a.file.Name()
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return removed, fmt.Errorf("rewrite analytics log: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return removed, fmt.Errorf("reopen analytics log: %w", err)
+	}
+	a.file.Close()
+	a.file = file
+	return removed, nil
+}
+
+// recordRequest adds a processed transcript request to the analytics log, if
+// enabled.
+func (s *Service) recordRequest(req *http.Request, svcReq TranscriptRequest, resp TranscriptResponse, err error, latency time.Duration) {
+	if s.analytics == nil {
+		return
+	}
+
+	record := RequestRecord{
+		Time:      time.Now().UTC(),
+		VideoID:   cmp.Or(resp.VideoID, svcReq.VideoID),
+		Title:     resp.Title,
+		Language:  cmp.Or(resp.Language, svcReq.Language),
+		Outcome:   requestOutcome(err),
+		Cache:     resp.Cache,
+		Error:     errorClass(err),
+		LatencyMs: milliseconds(latency),
+		Client:    clientNetwork(middleware.ClientIP(req)),
+	}
+	if addErr := s.analytics.add(record); addErr != nil {
+		s.logger.Warn("Failed to write analytics record", "error", addErr)
+	}
+}
+
+func requestOutcome(err error) string {
+	var validationErr *ValidationError
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrInvalidInterval), errors.As(err, &validationErr):
+		return OutcomeInvalid
+	case errors.Is(err, ErrNoTranscript):
+		return OutcomeNotFound
+	default:
+		return OutcomeError
+	}
+}
+
+// errorClass classifies the errors of requests that failed on the server or
+// upstream side, returning "" for any other outcome.
+func errorClass(err error) string {
+	if requestOutcome(err) != OutcomeError {
+		return ""
+	}
+	return youtube.ClassifyError(err)
+}
+
+// clientNetwork truncates ip to its /24 or /48 network so that the log does
+// not identify individual clients.
+func clientNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
+
+// Analytics summarizes the requests of the last window. ErrNotSupported is
+// returned when recording is disabled.
+func (s *Service) Analytics(window time.Duration) (AnalyticsReport, error) {
+	if s.analytics == nil {
+		return AnalyticsReport{}, ErrNotSupported
+	}
+
+	records := s.analytics.since(time.Now().UTC().Add(-window))
+	return analyticsReport(records, window), nil
+}
+
+// analyticsReport summarizes records, those of the last window.
+func analyticsReport(records []RequestRecord, window time.Duration) AnalyticsReport {
+	// Hourly traffic up to two days, daily beyond
+	bin := time.Hour
+	if window > 48*time.Hour {
+		bin = 24 * time.Hour
+	}
+
+	report := AnalyticsReport{
+		Window:    window.String(),
+		Requests:  len(records),
+		Outcomes:  make(map[string]int),
+		TopVideos: []VideoCount{},
+		Traffic:   []TrafficBin{},
+	}
+	clients := make(map[string]bool)
+	for _, record := range records {
+		report.Outcomes[record.Outcome]++
+		failed := record.Outcome == OutcomeError
+		if failed {
+			report.Failures++
+		}
+		if record.Client != "" {
+			clients[record.Client] = true
+		}
+
+		start := record.Time.Truncate(bin)
+		if n := len(report.Traffic); n == 0 || !report.Traffic[n-1].Start.Equal(start) {
+			report.Traffic = append(report.Traffic, TrafficBin{Start: start})
+		}
+		current := &report.Traffic[len(report.Traffic)-1]
+		current.Requests++
+		if failed {
+			current.Failures++
+		}
+	}
+	if report.Requests > 0 {
+		report.FailureRate = float64(report.Failures) / float64(report.Requests)
+	}
+	report.Clients = len(clients)
+	report.TopVideos = topVideos(records, maxTopVideos)
+	return report
+}
+
+// topVideos returns up to n videos successfully requested in records by
+// descending request count.
+func topVideos(records []RequestRecord, n int) []VideoCount {
+	videos := make(map[string]*VideoCount)
+	for _, record := range records {
+		if record.VideoID == "" || record.Outcome != OutcomeOK {
+			continue
+		}
+		video, ok := videos[record.VideoID]
+		if !ok {
+			video = &VideoCount{VideoID: record.VideoID}
+			videos[record.VideoID] = video
+		}
+		video.Requests++
+		video.Title = cmp.Or(record.Title, video.Title)
+	}
+
+	top := make([]VideoCount, 0, len(videos))
+	for _, video := range videos {
+		top = append(top, *video)
+	}
+	slices.SortFunc(top, func(a, b VideoCount) int {
+		return cmp.Or(cmp.Compare(b.Requests, a.Requests), cmp.Compare(a.VideoID, b.VideoID))
+	})
+	return top[:max(0, min(n, len(top)))]
+}
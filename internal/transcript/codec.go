@@ -0,0 +1,185 @@
+package transcript
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Stored transcripts start with a byte naming the encoding of the rest, so
+// that the encoding can change without invalidating existing entries.
+const (
+	codecJSON     byte = 1
+	codecGzipJSON byte = 2
+	// codecSealed is followed by a nonce and an AES-GCM sealed blob of one of
+	// the other codecs
+	codecSealed byte = 3
+)
+
+// schemaVersion is the version of the stored record layout. Bump it when a
+// change to youtube.TranscriptResponse needs existing records rewritten and
+// add the step to migrations.
+//
+// Version 1 records are a bare TranscriptResponse object without a version.
+// Version 2 wraps the transcript in a storedRecord.
+const schemaVersion = 2
+
+// storedRecord is the JSON layout of an encoded transcript
+type storedRecord struct {
+	Schema     int             `json:"schema"`
+	Transcript json.RawMessage `json:"transcript"`
+}
+
+// migrations[v] upgrades a transcript object from schema v to v+1 in place.
+// Steps that only change the envelope, like 1 to 2, need no entry.
+var migrations = map[int]func(transcript map[string]any) error{}
+
+// newCipher returns the AES-GCM cipher used to seal stored transcripts. key
+// must be 16, 24 or 32 bytes long.
+func newCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encodeTranscript serializes t as JSON, gzip compressed when compress is set
+// and sealed with aead when it is not nil.
+func encodeTranscript(t *youtube.TranscriptResponse, compress bool, aead cipher.AEAD) ([]byte, error) {
+	blob, err := encodePlain(t, compress)
+	if err != nil || aead == nil {
+		return blob, err
+	}
+
+	sealed := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(blob)+aead.Overhead())
+	sealed[0] = codecSealed
+	if _, err := rand.Read(sealed[1:]); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(sealed, sealed[1:], blob, nil), nil
+}
+
+func encodePlain(t *youtube.TranscriptResponse, compress bool) ([]byte, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("encode transcript: %w", err)
+	}
+	record := storedRecord{Schema: schemaVersion, Transcript: payload}
+
+	var buf bytes.Buffer
+	if !compress {
+		buf.WriteByte(codecJSON)
+		if err := json.NewEncoder(&buf).Encode(record); err != nil {
+			return nil, fmt.Errorf("encode transcript: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	buf.WriteByte(codecGzipJSON)
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(zw).Encode(record); err != nil {
+		return nil, fmt.Errorf("encode transcript: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compress transcript: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeTranscript reverses encodeTranscript for any known codec and schema
+// version, migrating older records to the current schema. Sealed blobs need
+// the aead they were sealed with.
+func decodeTranscript(blob []byte, aead cipher.AEAD) (*youtube.TranscriptResponse, error) {
+	if len(blob) == 0 {
+		return nil, ErrInvalidTranscript
+	}
+
+	if blob[0] == codecSealed {
+		if aead == nil {
+			return nil, fmt.Errorf("%w: transcript is encrypted but no key is set", ErrInvalidTranscript)
+		}
+		if len(blob) < 1+aead.NonceSize() {
+			return nil, fmt.Errorf("%w: sealed transcript is truncated", ErrInvalidTranscript)
+		}
+		nonce, sealed := blob[1:1+aead.NonceSize()], blob[1+aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decrypt transcript: %v", ErrInvalidTranscript, err)
+		}
+		if len(plain) > 0 && plain[0] == codecSealed {
+			return nil, fmt.Errorf("%w: nested sealed transcript", ErrInvalidTranscript)
+		}
+		return decodeTranscript(plain, nil)
+	}
+
+	var r io.Reader = bytes.NewReader(blob[1:])
+	switch blob[0] {
+	case codecJSON:
+	case codecGzipJSON:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("decompress transcript: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("%w: unknown codec %d", ErrInvalidTranscript, blob[0])
+	}
+
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	return migrateTranscript(payload)
+}
+
+// migrateTranscript decodes a stored record of any schema version
+func migrateTranscript(payload []byte) (*youtube.TranscriptResponse, error) {
+	var record storedRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return nil, fmt.Errorf("decode transcript: %w", err)
+	}
+	if record.Schema == 0 {
+		// Version 1 records are the transcript itself
+		record = storedRecord{Schema: 1, Transcript: payload}
+	}
+	if record.Schema > schemaVersion {
+		return nil, fmt.Errorf("%w: schema %d is newer than %d", ErrInvalidTranscript, record.Schema, schemaVersion)
+	}
+
+	if record.Schema < schemaVersion {
+		var transcript map[string]any
+		if err := json.Unmarshal(record.Transcript, &transcript); err != nil {
+			return nil, fmt.Errorf("decode transcript: %w", err)
+		}
+		for v := record.Schema; v < schemaVersion; v++ {
+			if migrate, ok := migrations[v]; ok {
+				if err := migrate(transcript); err != nil {
+					return nil, fmt.Errorf("migrate transcript from schema %d: %w", v, err)
+				}
+			}
+		}
+		migrated, err := json.Marshal(transcript)
+		if err != nil {
+			return nil, fmt.Errorf("encode migrated transcript: %w", err)
+		}
+		record.Transcript = migrated
+	}
+
+	var t youtube.TranscriptResponse
+	if err := json.Unmarshal(record.Transcript, &t); err != nil {
+		return nil, fmt.Errorf("decode transcript: %w", err)
+	}
+	return &t, nil
+}
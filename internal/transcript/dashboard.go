@@ -0,0 +1,90 @@
+package transcript
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// maxTopErrors bounds the error classes listed on the dashboard
+const maxTopErrors = 10
+
+// Dashboard aggregates the analytics log of a trailing window for an
+// operator dashboard
+type Dashboard struct {
+	AnalyticsReport
+	Cache     CacheEfficiency `json:"cache"`
+	TopErrors []ErrorCount    `json:"topErrors"`
+}
+
+// CacheEfficiency tells how many successful requests were served from the
+// cache instead of fetching upstream
+type CacheEfficiency struct {
+	Hits   int `json:"hits"`
+	Stale  int `json:"stale"`
+	Misses int `json:"misses"`
+	// HitRate is the share of hits and stale hits among the three
+	HitRate float64 `json:"hitRate"`
+}
+
+// ErrorCount is how often requests failed with an error class
+type ErrorCount struct {
+	// Class is a youtube.ClassifyError class, or other for records logged
+	// before classes were recorded
+	Class    string    `json:"class"`
+	Requests int       `json:"requests"`
+	Last     time.Time `json:"last"`
+}
+
+// Dashboard summarizes the requests of the last window like Analytics, along
+// with cache efficiency and the most frequent error classes. ErrNotSupported
+// is returned when recording is disabled.
+func (s *Service) Dashboard(window time.Duration) (Dashboard, error) {
+	if s.analytics == nil {
+		return Dashboard{}, ErrNotSupported
+	}
+
+	records := s.analytics.since(time.Now().UTC().Add(-window))
+	dashboard := Dashboard{
+		AnalyticsReport: analyticsReport(records, window),
+		TopErrors:       []ErrorCount{},
+	}
+
+	errs := make(map[string]*ErrorCount)
+	for _, record := range records {
+		switch record.Cache {
+		case CacheHit:
+			dashboard.Cache.Hits++
+		case CacheStale:
+			dashboard.Cache.Stale++
+		case CacheMiss:
+			dashboard.Cache.Misses++
+		}
+
+		if record.Outcome != OutcomeError {
+			continue
+		}
+		class := cmp.Or(record.Error, youtube.ErrorClassOther)
+		count, ok := errs[class]
+		if !ok {
+			count = &ErrorCount{Class: class}
+			errs[class] = count
+		}
+		count.Requests++
+		count.Last = record.Time
+	}
+	if served := dashboard.Cache.Hits + dashboard.Cache.Stale + dashboard.Cache.Misses; served > 0 {
+		dashboard.Cache.HitRate = float64(dashboard.Cache.Hits+dashboard.Cache.Stale) / float64(served)
+	}
+
+	for _, count := range errs {
+		dashboard.TopErrors = append(dashboard.TopErrors, *count)
+	}
+	slices.SortFunc(dashboard.TopErrors, func(a, b ErrorCount) int {
+		return cmp.Or(cmp.Compare(b.Requests, a.Requests), cmp.Compare(a.Class, b.Class))
+	})
+	dashboard.TopErrors = dashboard.TopErrors[:min(maxTopErrors, len(dashboard.TopErrors))]
+	return dashboard, nil
+}
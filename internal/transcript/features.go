@@ -0,0 +1,25 @@
+package transcript
+
+// FeatureSearchIndex indexes transcripts into the cluster set with
+// SetSearchIndex. It is on by default and can be switched off while the
+// cluster is unavailable.
+const FeatureSearchIndex = "search_index"
+
+// Features switches service behavior at runtime
+type Features interface {
+	// Enabled reports whether the feature name is on, def when unset
+	Enabled(name string, def bool) bool
+}
+
+// SetFeatures checks features whenever a switchable feature is used.
+func (s *Service) SetFeatures(features Features) {
+	s.features = features
+}
+
+// feature reports whether name is enabled, def without Features.
+func (s *Service) feature(name string, def bool) bool {
+	if s.features == nil {
+		return def
+	}
+	return s.features.Enabled(name, def)
+}
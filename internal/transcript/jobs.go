@@ -0,0 +1,308 @@
+package transcript
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+const (
+	// MaxPendingJobs bounds the transcript requests running in the
+	// background. Beyond it requests block as if sent synchronously.
+	MaxPendingJobs = 1000
+	// MaxJobs bounds the jobs kept, pending or finished. Beyond it the
+	// oldest results are dropped before they expire.
+	MaxJobs = 10000
+	// jobTTL is how long the result of a finished job can be fetched
+	jobTTL = 10 * time.Minute
+	// jobTimeout bounds a single background transcript request
+	jobTimeout = 5 * time.Minute
+	// MaxIdempotencyKeyLength bounds the Idempotency-Key of a submission
+	MaxIdempotencyKeyLength = 255
+	// upstreamHost is the host whose rate limiter queue decides whether a
+	// request runs in the background
+	upstreamHost = "www.youtube.com"
+)
+
+// ErrIdempotencyKeyReused is returned by SubmitIfQueued for an idempotency
+// key that was sent with a different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+
+// JobStatus is the state of a background transcript request
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Job describes a transcript request running in the background because
+// upstream requests were queued by the rate limiter
+type Job struct {
+	ID     string    `json:"id"`
+	Status JobStatus `json:"status"`
+	// Position is 1 for the oldest pending job, 0 once the job finished
+	Position int `json:"position,omitempty"`
+	// EstimatedWaitMs estimates the time until the job's first upstream
+	// request is sent
+	EstimatedWaitMs int64               `json:"estimatedWaitMs"`
+	Result          *TranscriptResponse `json:"result,omitempty"`
+	// Error is the message of a failed job, in the client's language
+	Error string `json:"error,omitempty"`
+
+	err error
+}
+
+// RateLimited is implemented by fetchers pacing their upstream requests,
+// such as the YouTube client.
+type RateLimited interface {
+	RateLimiter() *youtube.RateLimiter
+}
+
+var _ RateLimited = (*youtube.Client)(nil)
+
+type job struct {
+	id string
+	// key is the client's idempotency key, fingerprint identifies the
+	// request submitted with it
+	key         string
+	fingerprint [sha256.Size]byte
+	// readyAt is when the job's first upstream request was expected to be
+	// sent, as estimated on submission
+	readyAt  time.Time
+	finished time.Time
+	resp     TranscriptResponse
+	err      error
+	// el is the job's element in the pending list, then in the finished
+	// list
+	el *list.Element
+}
+
+// jobQueue holds background transcript requests until their results expire
+type jobQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	// keys maps idempotency keys to the jobs they submitted
+	keys map[string]*job
+	// pending holds unfinished jobs in submission order, finished holds the
+	// others in the order they finished
+	pending  *list.List
+	finished *list.List
+}
+
+func newJobQueue() *jobQueue {
+	return &jobQueue{
+		jobs:     make(map[string]*job),
+		keys:     make(map[string]*job),
+		pending:  list.New(),
+		finished: list.New(),
+	}
+}
+
+// upstreamWait reports the upstream requests waiting for the fetcher's rate
+// limiter and how long a new one would wait.
+func (s *Service) upstreamWait() (waiting int, wait time.Duration) {
+	limited, ok := s.fetcher.(RateLimited)
+	if !ok || limited.RateLimiter() == nil {
+		return 0, 0
+	}
+	return limited.RateLimiter().Estimate(upstreamHost)
+}
+
+// SubmitIfQueued starts req in the background and returns its job when the
+// request would wait for the rate limiter. Cached transcripts and requests
+// beyond MaxPendingJobs are not queued and reported false. done is called
+// with the outcome when the job finishes.
+//
+// A non-empty key makes retries idempotent: while its job is kept, the same
+// request submitted with key gets that job instead of a new one, and a
+// different request fails with ErrIdempotencyKeyReused.
+func (s *Service) SubmitIfQueued(ctx context.Context, req TranscriptRequest, key string, done func(TranscriptResponse, error)) (Job, bool, error) {
+	q := s.jobs
+	fingerprint := requestFingerprint(req)
+	if key != "" {
+		q.mu.Lock()
+		j, err := q.byKey(key, fingerprint, time.Now())
+		q.mu.Unlock()
+		if err != nil || j != nil {
+			return s.jobState(j), j != nil, err
+		}
+	}
+
+	_, wait := s.upstreamWait()
+	if wait <= 0 || s.cached(ctx, req) {
+		return Job{}, false, nil
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		s.logger.Warn("Failed to create job ID", "error", err)
+		return Job{}, false, nil
+	}
+
+	q.mu.Lock()
+	now := time.Now()
+	q.prune(now)
+	// A concurrent retry may have submitted the key meanwhile
+	if existing, err := q.byKey(key, fingerprint, now); err != nil || existing != nil {
+		q.mu.Unlock()
+		return s.jobState(existing), existing != nil, err
+	}
+	if q.pending.Len() >= MaxPendingJobs {
+		q.mu.Unlock()
+		return Job{}, false, nil
+	}
+	j := &job{id: id, key: key, fingerprint: fingerprint, readyAt: now.Add(wait)}
+	j.el = q.pending.PushBack(j)
+	q.jobs[id] = j
+	if key != "" {
+		q.keys[key] = j
+	}
+	q.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobTimeout)
+		defer cancel()
+
+		resp, err := s.GetTranscripts(ctx, req)
+		q.finish(j, resp, err)
+		if done != nil {
+			done(resp, err)
+		}
+	}()
+
+	return s.jobState(j), true, nil
+}
+
+// Job returns the state of the job with id, false when it is unknown or its
+// result expired.
+func (s *Service) Job(id string) (Job, bool) {
+	q := s.jobs
+	q.mu.Lock()
+	q.prune(time.Now())
+	j, ok := q.jobs[id]
+	q.mu.Unlock()
+	if !ok {
+		return Job{}, false
+	}
+	return s.jobState(j), true
+}
+
+// jobState describes j, which may have finished since it was looked up
+func (s *Service) jobState(j *job) Job {
+	if j == nil {
+		return Job{}
+	}
+	q := s.jobs
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if j.finished.IsZero() {
+		position := 1
+		for el := q.pending.Front(); el != j.el; el = el.Next() {
+			position++
+		}
+		return Job{
+			ID:              j.id,
+			Status:          JobPending,
+			Position:        position,
+			EstimatedWaitMs: max(time.Until(j.readyAt), 0).Milliseconds(),
+		}
+	}
+
+	if j.err != nil {
+		return Job{ID: j.id, Status: JobFailed, err: j.err}
+	}
+	resp := j.resp
+	return Job{ID: j.id, Status: JobDone, Result: &resp}
+}
+
+// byKey returns the kept job submitted with key, nil when there is none. It
+// must be called with q.mu held.
+func (q *jobQueue) byKey(key string, fingerprint [sha256.Size]byte, now time.Time) (*job, error) {
+	if key == "" {
+		return nil, nil
+	}
+	q.prune(now)
+	j, ok := q.keys[key]
+	if !ok {
+		return nil, nil
+	}
+	if j.fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return j, nil
+}
+
+// finish stores the outcome of j, dropping the oldest results beyond MaxJobs
+func (q *jobQueue) finish(j *job, resp TranscriptResponse, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j.resp, j.err = resp, err
+	j.finished = time.Now()
+	q.pending.Remove(j.el)
+	j.el = q.finished.PushBack(j)
+	for len(q.jobs) > MaxJobs {
+		q.remove(q.finished.Front())
+	}
+}
+
+// prune removes jobs whose results expired, the oldest first. It must be
+// called with q.mu held.
+func (q *jobQueue) prune(now time.Time) {
+	for el := q.finished.Front(); el != nil && now.Sub(el.Value.(*job).finished) > jobTTL; el = q.finished.Front() {
+		q.remove(el)
+	}
+}
+
+// remove forgets the finished job at el. It must be called with q.mu held.
+func (q *jobQueue) remove(el *list.Element) {
+	j := q.finished.Remove(el).(*job)
+	delete(q.jobs, j.id)
+	if j.key != "" {
+		delete(q.keys, j.key)
+	}
+}
+
+// requestFingerprint identifies req among the requests submitted with an
+// idempotency key
+func requestFingerprint(req TranscriptRequest) [sha256.Size]byte {
+	// TranscriptRequest only holds plain values, which always encode
+	data, _ := json.Marshal(req)
+	return sha256.Sum256(data)
+}
+
+// cached reports whether the transcript for req is cached, so that serving
+// it does not wait for upstream requests.
+func (s *Service) cached(ctx context.Context, req TranscriptRequest) bool {
+	if req.Account {
+		return false
+	}
+	if req.VideoID == "" {
+		req.VideoID = s.ExtractVideoId(req.VideoURL)
+		if req.VideoID == "" {
+			return false
+		}
+	}
+	_, err := s.repo.Stat(ctx, req.cacheKey())
+	return err == nil
+}
+
+// newJobID returns a random, unguessable job ID
+func newJobID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,125 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// limitedFetcher serves testTranscript after waiting for its rate limiter
+type limitedFetcher struct {
+	limiter *youtube.RateLimiter
+}
+
+func (f limitedFetcher) RateLimiter() *youtube.RateLimiter {
+	return f.limiter
+}
+
+func (f limitedFetcher) GetTranscript(ctx context.Context, videoID string, opts ...youtube.RequestOption) (*youtube.TranscriptResponse, error) {
+	if err := f.limiter.Wait(ctx, upstreamHost); err != nil {
+		return nil, err
+	}
+	return testTranscript(), nil
+}
+
+func TestAsyncTranscriptJob(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := youtube.NewRateLimiter(0, 200*time.Millisecond)
+	mux := NewRouter(NewService(limitedFetcher{limiter}, NewMemoryRepository(logger), logger), nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Prefer", "respond-async")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// Nothing is queued yet, so the first request is answered directly
+	if w := get("/api/v1/transcripts?videoId=first"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", w.Code)
+	}
+
+	w := get("/api/v1/transcripts?videoId=second")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("queued request: status %d, want 202", w.Code)
+	}
+	var job Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobPending || job.Position != 1 || job.EstimatedWaitMs <= 0 {
+		t.Errorf("queued job = %+v, want pending at position 1 with a wait", job)
+	}
+	location := w.Header().Get("Location")
+	if location != "/api/v1/jobs/"+job.ID {
+		t.Fatalf("Location = %q", location)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == JobPending && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		w = get(location)
+		if w.Code != http.StatusOK {
+			t.Fatalf("poll: status %d, want 200", w.Code)
+		}
+		job = Job{}
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != JobDone || job.Result == nil || job.Result.VideoID != "second" {
+		t.Fatalf("finished job = %+v, want done with the transcript of second", job)
+	}
+
+	if w := get("/api/v1/jobs/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown job: status %d, want 404", w.Code)
+	}
+}
+
+func TestAsyncTranscriptIdempotencyKey(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := youtube.NewRateLimiter(0, 200*time.Millisecond)
+	mux := NewRouter(NewService(limitedFetcher{limiter}, NewMemoryRepository(logger), logger), nil)
+
+	submit := func(videoID, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/transcripts?videoId="+videoID, nil)
+		req.Header.Set("Prefer", "respond-async")
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	jobID := func(w *httptest.ResponseRecorder) string {
+		t.Helper()
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("status %d, want 202", w.Code)
+		}
+		var job Job
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+		return job.ID
+	}
+
+	// Keep the rate limiter busy so that the submissions are queued
+	submit("first", "")
+
+	id := jobID(submit("second", "retry-me"))
+	if retried := jobID(submit("second", "retry-me")); retried != id {
+		t.Errorf("retry got job %q, want %q", retried, id)
+	}
+	if other := jobID(submit("third", "another")); other == id {
+		t.Error("another key got the same job")
+	}
+	if w := submit("fourth", "retry-me"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key: status %d, want 422", w.Code)
+	}
+}
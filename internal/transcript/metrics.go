@@ -0,0 +1,199 @@
+package transcript
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// metricsWindow is how far back error rates are kept, in one minute buckets
+const metricsWindow = 60
+
+// errorRateWindows are the spans ErrorRates reports, in minutes
+var errorRateWindows = []int{1, 5, 15, 60}
+
+// RejectInvalidURL counts requests refused because of an invalid video URL
+const RejectInvalidURL = "invalid_url"
+
+// metrics counts upstream fetches by outcome and requests rejected before
+// reaching YouTube, both in total and per minute for rolling error rates
+type metrics struct {
+	mu       sync.Mutex
+	requests int64
+	errors   map[string]int64
+	rejected map[string]int64
+	buckets  [metricsWindow]metricsBucket
+}
+
+type metricsBucket struct {
+	minute   int64
+	requests int
+	errors   map[string]int
+	rejected map[string]int
+}
+
+// ErrorRate summarizes upstream outcomes over a trailing window
+type ErrorRate struct {
+	Window   string  `json:"window"`
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+	Rate     float64 `json:"errorRate"`
+	// Classes counts errors by youtube.ClassifyError class
+	Classes map[string]int `json:"classes"`
+	// Rejected counts requests refused before reaching YouTube by reason
+	Rejected map[string]int `json:"rejected"`
+}
+
+func newMetrics() *metrics {
+	return &metrics{errors: make(map[string]int64), rejected: make(map[string]int64)}
+}
+
+// bucket returns the bucket of now's minute, resetting it if it is stale.
+// The caller holds mu.
+func (m *metrics) bucket(now time.Time) *metricsBucket {
+	minute := now.Unix() / 60
+	b := &m.buckets[minute%metricsWindow]
+	if b.minute != minute {
+		*b = metricsBucket{minute: minute, errors: make(map[string]int), rejected: make(map[string]int)}
+	}
+	return b
+}
+
+// recordUpstream counts an upstream fetch that failed with err, or succeeded
+// when err is nil.
+func (m *metrics) recordUpstream(err error) {
+	m.recordClass(youtube.ClassifyError(err))
+}
+
+// recordClass counts an upstream fetch with an error class, "" for success.
+func (m *metrics) recordClass(class string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.bucket(time.Now())
+	m.requests++
+	b.requests++
+	if class != "" {
+		m.errors[class]++
+		b.errors[class]++
+	}
+}
+
+// recordRejected counts a request refused before reaching YouTube.
+func (m *metrics) recordRejected(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rejected[reason]++
+	m.bucket(time.Now()).rejected[reason]++
+}
+
+// errorRates sums the buckets of each of errorRateWindows up to now.
+func (m *metrics) errorRates(now time.Time) []ErrorRate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := now.Unix() / 60
+	rates := make([]ErrorRate, 0, len(errorRateWindows))
+	for _, window := range errorRateWindows {
+		rate := ErrorRate{
+			Window:   fmt.Sprintf("%dm", window),
+			Classes:  make(map[string]int),
+			Rejected: make(map[string]int),
+		}
+		for _, b := range m.buckets {
+			if b.minute == 0 || current-b.minute >= int64(window) || b.minute > current {
+				continue
+			}
+			rate.Requests += b.requests
+			for class, n := range b.errors {
+				rate.Classes[class] += n
+				rate.Errors += n
+			}
+			for reason, n := range b.rejected {
+				rate.Rejected[reason] += n
+			}
+		}
+		if rate.Requests > 0 {
+			rate.Rate = float64(rate.Errors) / float64(rate.Requests)
+		}
+		rates = append(rates, rate)
+	}
+	return rates
+}
+
+// ErrorRates reports upstream error rates over the last 1, 5, 15 and 60
+// minutes.
+func (s *Service) ErrorRates() []ErrorRate {
+	return s.metrics.errorRates(time.Now())
+}
+
+// WritePrometheus writes the upstream counters and cache gauges in the
+// Prometheus text exposition format.
+func (s *Service) WritePrometheus(w io.Writer) error {
+	s.metrics.mu.Lock()
+	requests := s.metrics.requests
+	errs := make(map[string]int64, len(s.metrics.errors))
+	for class, n := range s.metrics.errors {
+		errs[class] = n
+	}
+	rejected := make(map[string]int64, len(s.metrics.rejected))
+	for reason, n := range s.metrics.rejected {
+		rejected[reason] = n
+	}
+	s.metrics.mu.Unlock()
+
+	// Report every class so that series exist before the first error
+	for _, class := range youtube.ErrorClasses() {
+		errs[class] += 0
+	}
+	rejected[RejectInvalidURL] += 0
+
+	ew := &errWriter{w: w}
+	ew.printf("# HELP youtube_summary_upstream_requests_total Caption fetches passed to the YouTube client.\n")
+	ew.printf("# TYPE youtube_summary_upstream_requests_total counter\n")
+	ew.printf("youtube_summary_upstream_requests_total %d\n", requests)
+	ew.printf("# HELP youtube_summary_upstream_errors_total Failed caption fetches by error class.\n")
+	ew.printf("# TYPE youtube_summary_upstream_errors_total counter\n")
+	for _, class := range sortedKeys(errs) {
+		ew.printf("youtube_summary_upstream_errors_total{class=%q} %d\n", class, errs[class])
+	}
+	ew.printf("# HELP youtube_summary_rejected_requests_total Requests refused before reaching YouTube by reason.\n")
+	ew.printf("# TYPE youtube_summary_rejected_requests_total counter\n")
+	for _, reason := range sortedKeys(rejected) {
+		ew.printf("youtube_summary_rejected_requests_total{reason=%q} %d\n", reason, rejected[reason])
+	}
+	ew.printf("# HELP youtube_summary_cache_entries Transcripts held in the cache.\n")
+	ew.printf("# TYPE youtube_summary_cache_entries gauge\n")
+	ew.printf("youtube_summary_cache_entries %d\n", s.repo.Size())
+	ew.printf("# HELP youtube_summary_cache_bytes Approximate memory used by cached transcripts.\n")
+	ew.printf("# TYPE youtube_summary_cache_bytes gauge\n")
+	ew.printf("youtube_summary_cache_bytes %d\n", s.repo.Bytes())
+	return ew.err
+}
+
+// errWriter keeps the first write error so that a sequence of writes can be
+// checked once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
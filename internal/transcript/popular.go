@@ -0,0 +1,56 @@
+package transcript
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultPopularWindow is the span popular videos are counted over
+	DefaultPopularWindow = 24 * time.Hour
+	// MaxPopularWindow bounds the span of a popular videos request
+	MaxPopularWindow = 30 * 24 * time.Hour
+	// DefaultPopularLimit is the number of popular videos listed by default
+	DefaultPopularLimit = 10
+	// MaxPopularLimit bounds the number of popular videos listed
+	MaxPopularLimit = 50
+)
+
+// PopularVideos is served at /api/v1/stats/popular
+type PopularVideos struct {
+	Window string         `json:"window"`
+	Videos []PopularVideo `json:"videos"`
+}
+
+// PopularVideo is a frequently requested video and its cached transcript
+type PopularVideo struct {
+	VideoCount
+	Transcript TranscriptStatus `json:"transcript"`
+}
+
+// SetPopularVisible publishes the most requested videos from the analytics
+// log at /api/v1/stats/popular. It is off by default since the list reveals
+// what users of the instance watch.
+func (s *Service) SetPopularVisible(visible bool) {
+	s.popularVisible = visible
+}
+
+// Popular lists up to limit videos most requested within window, along with
+// whether their transcripts are cached. ErrNotSupported is returned unless
+// analytics are recorded and the list is made visible.
+func (s *Service) Popular(ctx context.Context, window time.Duration, limit int) (PopularVideos, error) {
+	if s.analytics == nil || !s.popularVisible {
+		return PopularVideos{}, ErrNotSupported
+	}
+
+	records := s.analytics.since(time.Now().UTC().Add(-window))
+	popular := PopularVideos{Window: window.String(), Videos: []PopularVideo{}}
+	for _, video := range topVideos(records, limit) {
+		status, err := s.Status(ctx, video.VideoID)
+		if err != nil {
+			return PopularVideos{}, err
+		}
+		popular.Videos = append(popular.Videos, PopularVideo{VideoCount: video, Transcript: status.Transcript})
+	}
+	return popular, nil
+}
@@ -0,0 +1,77 @@
+package transcript
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+)
+
+// PurgeResult counts the artifacts removed for a deletion request
+type PurgeResult struct {
+	VideoID string `json:"videoId,omitempty"`
+	// Client is the client network whose records were removed
+	Client           string `json:"client,omitempty"`
+	CacheEntries     int    `json:"cacheEntries"`
+	VaultNotes       int    `json:"vaultNotes"`
+	AnalyticsRecords int    `json:"analyticsRecords"`
+	SearchDocuments  int    `json:"searchDocuments"`
+}
+
+// PurgeVideo removes everything stored about videoID: cached transcripts in
+// every language, vault notes, search documents and analytics records.
+func (s *Service) PurgeVideo(ctx context.Context, videoID string) (PurgeResult, error) {
+	if !videoIDPattern.MatchString(videoID) {
+		return PurgeResult{}, &ValidationError{Fields: []FieldError{NewFieldError("id", i18n.InvalidVideoID)}}
+	}
+	result := PurgeResult{VideoID: videoID}
+
+	var err error
+	if result.CacheEntries, err = s.repo.Delete(ctx, videoID); err != nil {
+		return result, fmt.Errorf("delete cached transcripts: %w", err)
+	}
+	if result.VaultNotes, err = s.vault.delete(videoID); err != nil {
+		return result, err
+	}
+	if result.SearchDocuments, err = s.search.delete(ctx, videoID); err != nil {
+		return result, err
+	}
+	if s.analytics != nil {
+		result.AnalyticsRecords, err = s.analytics.remove(func(r RequestRecord) bool {
+			return r.VideoID == videoID
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	s.logger.Info("Purged video", "video_id", videoID, "cache_entries", result.CacheEntries,
+		"vault_notes", result.VaultNotes, "search_documents", result.SearchDocuments,
+		"analytics_records", result.AnalyticsRecords)
+	return result, nil
+}
+
+// PurgeClient removes the analytics records of the client with ip. Records
+// are only kept per client network, so those of other clients in the same
+// /24 or /48 network are removed as well.
+func (s *Service) PurgeClient(ip string) (PurgeResult, error) {
+	network := clientNetwork(ip)
+	if network == "" {
+		return PurgeResult{}, ErrInvalidClient
+	}
+	result := PurgeResult{Client: network}
+	if s.analytics == nil {
+		return result, nil
+	}
+
+	var err error
+	result.AnalyticsRecords, err = s.analytics.remove(func(r RequestRecord) bool {
+		return r.Client == network
+	})
+	if err != nil {
+		return result, err
+	}
+
+	s.logger.Info("Purged client", "client", network, "analytics_records", result.AnalyticsRecords)
+	return result, nil
+}
@@ -1,10 +1,16 @@
 package transcript
 
 import (
+	"container/list"
 	"context"
+	"crypto/cipher"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
 )
@@ -14,17 +20,153 @@ var (
 	ErrInvalidTranscript  = errors.New("invalid transcript")
 )
 
+// CacheStatus tells how GetOrFetch satisfied a lookup
+type CacheStatus string
+
+const (
+	// CacheHit is a fresh cached transcript
+	CacheHit CacheStatus = "HIT"
+	// CacheMiss is a transcript fetched upstream for this lookup
+	CacheMiss CacheStatus = "MISS"
+	// CacheStale is an expired transcript served while it is refreshed in
+	// the background
+	CacheStale CacheStatus = "STALE"
+)
+
+// refreshBackoff is how long a stale entry whose refresh failed is served
+// before its refresh is retried
+const refreshBackoff = time.Minute
+
+// FetchFunc loads a transcript from its upstream source on a cache miss.
+type FetchFunc func(ctx context.Context) (*youtube.TranscriptResponse, error)
+
 type Repository interface {
 	Get(ctx context.Context, videoID string) (*youtube.TranscriptResponse, error)
 	Save(ctx context.Context, videoID string, transcript *youtube.TranscriptResponse) error
+	// GetOrFetch returns the cached transcript for videoID or calls fetch and
+	// caches its result. Concurrent misses for the same video share a single
+	// fetch.
+	GetOrFetch(ctx context.Context, videoID string, fetch FetchFunc) (*youtube.TranscriptResponse, CacheStatus, error)
+	// Stat describes the cached transcript for videoID without copying it.
+	Stat(ctx context.Context, videoID string) (EntryInfo, error)
+	// Delete removes every cached transcript of videoID, including those
+	// for other languages and regions, and returns how many were removed.
+	Delete(ctx context.Context, videoID string) (int, error)
 	Clear(ctx context.Context) error
+	// Keys lists the keys of all cached transcripts
+	Keys() []string
+	// Size is the number of cached transcripts
 	Size() int
+	// Bytes approximates the memory held by cached transcripts
+	Bytes() int64
+}
+
+// EntryInfo describes a cached transcript
+type EntryInfo struct {
+	VideoID  string
+	Language string
+	Source   string
+	Segments int
+	CachedAt time.Time
+}
+
+// memoryEntry holds either the transcript itself or, with compression or
+// encryption enabled, its encoded form. Stat details are kept alongside so
+// that they can be read without decoding.
+type memoryEntry struct {
+	key        string
+	transcript *youtube.TranscriptResponse
+	blob       []byte
+	language   string
+	source     string
+	segments   int
+	cachedAt   time.Time
+	// expiresAt is zero for entries that never expire
+	expiresAt time.Time
+	// size approximates the bytes held by the entry
+	size int64
+	// refreshing is set while a background refresh of the stale entry is
+	// in flight, refreshFailed when the last one failed
+	refreshing    bool
+	refreshFailed time.Time
+}
+
+// load returns a deep copy of the stored transcript, opening sealed entries
+// with aead
+func (e memoryEntry) load(aead cipher.AEAD) (*youtube.TranscriptResponse, error) {
+	if e.blob != nil {
+		return decodeTranscript(e.blob, aead)
+	}
+	if e.transcript == nil {
+		return nil, ErrInvalidTranscript
+	}
+	return cloneTranscript(e.transcript), nil
+}
+
+// cloneTranscript copies t including its segment, formatted and chapter
+// slices, so that neither the cache nor its callers can modify the other's
+// data.
+func cloneTranscript(t *youtube.TranscriptResponse) *youtube.TranscriptResponse {
+	clone := *t
+	if t.Raw != nil {
+		clone.Raw = &youtube.Transcript{Segments: slices.Clone(t.Raw.Segments)}
+	}
+	clone.Formatted = slices.Clone(t.Formatted)
+	clone.Chapters = slices.Clone(t.Chapters)
+	return &clone
+}
+
+// Approximate fixed costs of cached values, in bytes
+const (
+	entryOverhead   = 256
+	segmentOverhead = 40
+	stringOverhead  = 16
+)
+
+// approxSize estimates the memory held by t
+func approxSize(t *youtube.TranscriptResponse) int64 {
+	size := int64(entryOverhead + len(t.Title) + len(t.Channel) + len(t.Language) + len(t.Source))
+	if t.Raw != nil {
+		for _, segment := range t.Raw.Segments {
+			size += int64(segmentOverhead + len(segment.Text))
+		}
+	}
+	for _, line := range t.Formatted {
+		size += int64(stringOverhead + len(line))
+	}
+	for _, chapter := range t.Chapters {
+		size += int64(segmentOverhead + len(chapter.Title))
+	}
+	return size
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
 }
 
 type MemoryRepository struct {
-	logger    *slog.Logger
-	cache     map[string]*youtube.TranscriptResponse
+	logger *slog.Logger
+	// cache holds the elements of order, whose values are *memoryEntry
+	cache     map[string]*list.Element
 	cacheLock sync.RWMutex
+	// order lists entries from the most to the least recently used. Readers
+	// holding the read lock only move entries, under orderLock.
+	order     *list.List
+	orderLock sync.Mutex
+	flight    flightGroup
+
+	ttl                  time.Duration
+	staleWhileRevalidate bool
+	compress             bool
+	// aead seals cached transcripts when encryption is enabled
+	aead cipher.AEAD
+	// codec counts changes of compress and aead, so that a save can tell
+	// whether its entry was encoded with the current settings
+	codec uint64
+
+	// bytes is the sum of entry sizes, bounded by maxBytes when positive
+	bytes    int64
+	maxBytes int64
 }
 
 var _ Repository = (*MemoryRepository)(nil)
@@ -36,10 +178,65 @@ func NewMemoryRepository(logger *slog.Logger) *MemoryRepository {
 
 	return &MemoryRepository{
 		logger: logger,
-		cache:  make(map[string]*youtube.TranscriptResponse),
+		cache:  make(map[string]*list.Element),
+		order:  list.New(),
 	}
 }
 
+// SetTTL expires transcripts cached by GetOrFetch after ttl, zero keeping them
+// forever. With staleWhileRevalidate, an expired transcript is still returned
+// while a background fetch refreshes it; otherwise it is fetched again before
+// returning. Transcripts stored with Save, such as uploads, never expire.
+func (r *MemoryRepository) SetTTL(ttl time.Duration, staleWhileRevalidate bool) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+	r.ttl = max(ttl, 0)
+	r.staleWhileRevalidate = staleWhileRevalidate
+}
+
+// SetCompression stores transcripts saved from now on gzip compressed, which
+// typically shrinks long transcripts by around 80% at the cost of decoding on
+// every read. Entries already cached keep their encoding.
+func (r *MemoryRepository) SetCompression(enabled bool) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+	r.compress = enabled
+	r.codec++
+}
+
+// SetEncryptionKey seals transcripts cached from now on with AES-GCM under
+// key, which must be 16, 24 or 32 bytes long. Entries cached before are
+// dropped, since they are either unencrypted or sealed with another key. A
+// nil key disables encryption.
+func (r *MemoryRepository) SetEncryptionKey(key []byte) error {
+	var aead cipher.AEAD
+	if key != nil {
+		var err error
+		if aead, err = newCipher(key); err != nil {
+			return fmt.Errorf("invalid cache encryption key: %w", err)
+		}
+	}
+
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+	r.aead = aead
+	r.codec++
+	r.cache = make(map[string]*list.Element)
+	r.order.Init()
+	r.bytes = 0
+	return nil
+}
+
+// SetMaxBytes bounds the approximate memory held by cached transcripts. When a
+// save exceeds the limit, the least recently used entries are evicted,
+// including uploaded transcripts. Zero or less removes the limit.
+func (r *MemoryRepository) SetMaxBytes(maxBytes int64) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+	r.maxBytes = max(maxBytes, 0)
+	r.evict("")
+}
+
 func (r *MemoryRepository) Get(ctx context.Context, videoID string) (*youtube.TranscriptResponse, error) {
 	if videoID == "" {
 		return nil, errors.New("video ID cannot be empty")
@@ -52,21 +249,21 @@ func (r *MemoryRepository) Get(ctx context.Context, videoID string) (*youtube.Tr
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		transcript, exists := r.cache[videoID]
+		entry, exists := r.entry(videoID, true)
 		if !exists {
 			r.logger.Debug("Cache miss", "video_id", videoID)
 			return nil, ErrTranscriptNotFound
 		}
 
-		if transcript == nil {
-			r.logger.Warn("Found nil transcript in cache", "video_id", videoID)
+		// Return a copy to prevent modifications to cached data
+		transcript, err := entry.load(r.aead)
+		if err != nil {
+			r.logger.Warn("Found invalid transcript in cache", "video_id", videoID, "error", err)
 			return nil, ErrInvalidTranscript
 		}
 
 		r.logger.Debug("Cache hit", "video_id", videoID)
-		// Return a copy to prevent modifications to cached data
-		transcriptCopy := *transcript
-		return &transcriptCopy, nil
+		return transcript, nil
 	}
 }
 
@@ -78,24 +275,297 @@ func (r *MemoryRepository) Save(ctx context.Context, videoID string, transcript
 		return ErrInvalidTranscript
 	}
 
-	r.cacheLock.Lock()
-	defer r.cacheLock.Unlock()
+	return r.save(ctx, videoID, transcript, 0)
+}
+
+// save stores a copy of transcript, expiring after ttl unless ttl is zero
+func (r *MemoryRepository) save(ctx context.Context, videoID string, transcript *youtube.TranscriptResponse, ttl time.Duration) error {
+	for {
+		r.cacheLock.RLock()
+		compress, aead, codec := r.compress, r.aead, r.codec
+		r.cacheLock.RUnlock()
+
+		// Encode outside the lock, compression is comparatively slow
+		entry, err := newMemoryEntry(transcript, ttl, compress, aead)
+		if err != nil {
+			return err
+		}
 
+		r.cacheLock.Lock()
+		// Encode again if the key was rotated meanwhile, as the entry could
+		// not be opened with the new one
+		if r.codec != codec {
+			r.cacheLock.Unlock()
+			continue
+		}
+		err = r.store(ctx, videoID, entry)
+		r.cacheLock.Unlock()
+		return err
+	}
+}
+
+// newMemoryEntry encodes a copy of transcript with compress and aead
+func newMemoryEntry(transcript *youtube.TranscriptResponse, ttl time.Duration, compress bool, aead cipher.AEAD) (memoryEntry, error) {
+	entry := memoryEntry{
+		language: transcript.Language,
+		source:   transcript.Source,
+		cachedAt: time.Now(),
+	}
+	if transcript.Raw != nil {
+		entry.segments = len(transcript.Raw.Segments)
+	}
+	if compress || aead != nil {
+		blob, err := encodeTranscript(transcript, compress, aead)
+		if err != nil {
+			return memoryEntry{}, err
+		}
+		entry.blob = blob
+		entry.size = int64(entryOverhead + len(blob))
+	} else {
+		// Make a copy of the transcript to prevent external modifications
+		entry.transcript = cloneTranscript(transcript)
+		entry.size = approxSize(transcript)
+	}
+	if ttl > 0 {
+		entry.expiresAt = entry.cachedAt.Add(ttl)
+	}
+	return entry, nil
+}
+
+// store caches entry under videoID. The caller must hold the write lock.
+func (r *MemoryRepository) store(ctx context.Context, videoID string, entry memoryEntry) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		// Make a copy of the transcript to prevent external modifications
-		transcriptCopy := *transcript
-		r.cache[videoID] = &transcriptCopy
+		entry.key = videoID
+		if el, ok := r.cache[videoID]; ok {
+			r.bytes += entry.size - el.Value.(*memoryEntry).size
+			el.Value = &entry
+			r.order.MoveToFront(el)
+		} else {
+			r.bytes += entry.size
+			r.cache[videoID] = r.order.PushFront(&entry)
+		}
+		r.evict(videoID)
 		r.logger.Debug("Cached transcript",
 			"video_id", videoID,
 			"cache_size", len(r.cache),
+			"cache_bytes", r.bytes,
 		)
 		return nil
 	}
 }
 
+// evict removes the least recently used entries other than keep until the
+// cache fits maxBytes. The caller must hold the write lock.
+func (r *MemoryRepository) evict(keep string) {
+	for el := r.order.Back(); el != nil && r.maxBytes > 0 && r.bytes > r.maxBytes; {
+		entry := el.Value.(*memoryEntry)
+		prev := el.Prev()
+		if entry.key != keep {
+			r.remove(el)
+			r.logger.Debug("Evicted transcript", "video_id", entry.key, "cache_bytes", r.bytes)
+		}
+		el = prev
+	}
+}
+
+// remove deletes the entry at el. The caller must hold the write lock.
+func (r *MemoryRepository) remove(el *list.Element) {
+	entry := r.order.Remove(el).(*memoryEntry)
+	r.bytes -= entry.size
+	delete(r.cache, entry.key)
+}
+
+// entry returns a copy of the entry for videoID, marking it as recently used
+// when touch is set. The caller must hold the read or write lock.
+func (r *MemoryRepository) entry(videoID string, touch bool) (memoryEntry, bool) {
+	el, ok := r.cache[videoID]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if touch {
+		r.orderLock.Lock()
+		r.order.MoveToFront(el)
+		r.orderLock.Unlock()
+	}
+	return *el.Value.(*memoryEntry), true
+}
+
+// lookup returns a copy of the cached transcript for videoID and whether it
+// has expired.
+func (r *MemoryRepository) lookup(videoID string) (*youtube.TranscriptResponse, bool, bool) {
+	r.cacheLock.RLock()
+	entry, exists := r.entry(videoID, true)
+	aead := r.aead
+	r.cacheLock.RUnlock()
+	if !exists {
+		return nil, false, false
+	}
+
+	transcript, err := entry.load(aead)
+	if err != nil {
+		r.logger.Warn("Found invalid transcript in cache", "video_id", videoID, "error", err)
+		return nil, false, false
+	}
+	return transcript, entry.expired(time.Now()), true
+}
+
+func (r *MemoryRepository) GetOrFetch(ctx context.Context, videoID string, fetch FetchFunc) (*youtube.TranscriptResponse, CacheStatus, error) {
+	if videoID == "" {
+		return nil, "", errors.New("video ID cannot be empty")
+	}
+
+	r.cacheLock.RLock()
+	ttl, staleWhileRevalidate := r.ttl, r.staleWhileRevalidate
+	r.cacheLock.RUnlock()
+
+	cached, expired, found := r.lookup(videoID)
+	switch {
+	case found && !expired:
+		r.logger.Debug("Cache hit", "video_id", videoID)
+		return cached, CacheHit, nil
+	case found && staleWhileRevalidate:
+		r.logger.Debug("Serving stale transcript", "video_id", videoID)
+		if r.claimRefresh(videoID) {
+			go r.refresh(context.WithoutCancel(ctx), videoID, fetch, ttl)
+		}
+		return cached, CacheStale, nil
+	}
+	r.logger.Debug("Cache miss", "video_id", videoID)
+
+	transcript, err := r.fetch(ctx, videoID, fetch, ttl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Every waiter gets its own copy of the shared result
+	return cloneTranscript(transcript), CacheMiss, nil
+}
+
+// fetch loads videoID upstream and caches it, sharing the fetch with
+// concurrent callers.
+func (r *MemoryRepository) fetch(ctx context.Context, videoID string, fetch FetchFunc, ttl time.Duration) (*youtube.TranscriptResponse, error) {
+	return r.flight.do(ctx, videoID, func() (*youtube.TranscriptResponse, error) {
+		// Another caller may have refreshed the cache while we were waiting
+		if cached, expired, found := r.lookup(videoID); found && !expired {
+			return cached, nil
+		}
+
+		// Detach from the caller's cancellation so that waiters sharing this
+		// fetch are not failed by the first caller going away
+		fetched, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if fetched == nil {
+			return nil, ErrInvalidTranscript
+		}
+
+		if err := r.save(ctx, videoID, fetched, ttl); err != nil {
+			r.logger.Error("Failed to cache transcript", "video_id", videoID, "error", err)
+			// Continue despite cache error
+		}
+		return fetched, nil
+	})
+}
+
+// refresh replaces a stale entry in the background. On failure the stale
+// entry is kept and served until a later refresh succeeds.
+func (r *MemoryRepository) refresh(ctx context.Context, videoID string, fetch FetchFunc, ttl time.Duration) {
+	_, err := r.fetch(ctx, videoID, fetch, ttl)
+	r.finishRefresh(videoID, err)
+	if err != nil {
+		r.logger.Warn("Failed to refresh stale transcript", "video_id", videoID, "error", err)
+		return
+	}
+	r.logger.Debug("Refreshed stale transcript", "video_id", videoID)
+}
+
+// claimRefresh marks the stale entry of videoID as refreshing and reports
+// whether the caller should refresh it: not when a refresh is in flight or
+// the last one failed less than refreshBackoff ago.
+func (r *MemoryRepository) claimRefresh(videoID string) bool {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	el, ok := r.cache[videoID]
+	if !ok {
+		return false
+	}
+	entry := el.Value.(*memoryEntry)
+	if entry.refreshing || time.Since(entry.refreshFailed) < refreshBackoff {
+		return false
+	}
+	entry.refreshing = true
+	return true
+}
+
+// finishRefresh clears the refreshing mark of videoID, recording when the
+// refresh failed. A successful refresh has already replaced the entry.
+func (r *MemoryRepository) finishRefresh(videoID string, err error) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	el, ok := r.cache[videoID]
+	if !ok {
+		return
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.refreshing {
+		return
+	}
+	entry.refreshing = false
+	if err != nil {
+		entry.refreshFailed = time.Now()
+	}
+}
+
+func (r *MemoryRepository) Stat(ctx context.Context, videoID string) (EntryInfo, error) {
+	r.cacheLock.RLock()
+	defer r.cacheLock.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return EntryInfo{}, ctx.Err()
+	default:
+		entry, exists := r.entry(videoID, false)
+		if !exists {
+			return EntryInfo{}, ErrTranscriptNotFound
+		}
+
+		return EntryInfo{
+			VideoID:  videoID,
+			Language: entry.language,
+			Source:   entry.source,
+			Segments: entry.segments,
+			CachedAt: entry.cachedAt,
+		}, nil
+	}
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, videoID string) (int, error) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		removed := 0
+		for key, el := range r.cache {
+			if key != videoID && !strings.HasPrefix(key, videoID+"@") && !strings.HasPrefix(key, videoID+"#") {
+				continue
+			}
+			r.remove(el)
+			removed++
+		}
+		r.logger.Info("Deleted transcripts", "video_id", videoID, "count", removed)
+		return removed, nil
+	}
+}
+
 func (r *MemoryRepository) Clear(ctx context.Context) error {
 	r.cacheLock.Lock()
 	defer r.cacheLock.Unlock()
@@ -104,14 +574,34 @@ func (r *MemoryRepository) Clear(ctx context.Context) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		r.cache = make(map[string]*youtube.TranscriptResponse)
+		r.cache = make(map[string]*list.Element)
+		r.order.Init()
+		r.bytes = 0
 		r.logger.Info("Cache cleared")
 		return nil
 	}
 }
 
+func (r *MemoryRepository) Keys() []string {
+	r.cacheLock.RLock()
+	defer r.cacheLock.RUnlock()
+
+	keys := make([]string, 0, len(r.cache))
+	for key := range r.cache {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 func (r *MemoryRepository) Size() int {
 	r.cacheLock.RLock()
 	defer r.cacheLock.RUnlock()
 	return len(r.cache)
 }
+
+func (r *MemoryRepository) Bytes() int64 {
+	r.cacheLock.RLock()
+	defer r.cacheLock.RUnlock()
+	return r.bytes
+}
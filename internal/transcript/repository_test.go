@@ -0,0 +1,182 @@
+package transcript
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+func testTranscript() *youtube.TranscriptResponse {
+	return &youtube.TranscriptResponse{
+		Title: "title",
+		Raw: &youtube.Transcript{Segments: []youtube.TranscriptSegment{
+			{Text: "first", StartTime: 0, Duration: 1},
+			{Text: "second", StartTime: 1, Duration: 1},
+		}},
+		Formatted: []string{"(00:00) first second"},
+		Chapters: []youtube.Chapter{
+			{Title: "Intro", Start: 0},
+			{Title: "Outro", Start: 1},
+		},
+	}
+}
+
+// mutate overwrites every shared slice element of t
+func mutate(t *youtube.TranscriptResponse, i int) {
+	for j := range t.Raw.Segments {
+		t.Raw.Segments[j].Text = fmt.Sprint("mutated ", i)
+	}
+	for j := range t.Formatted {
+		t.Formatted[j] = fmt.Sprint("mutated ", i)
+	}
+	for j := range t.Chapters {
+		t.Chapters[j].Title = fmt.Sprint("mutated ", i)
+	}
+}
+
+// checkUnchanged fails unless t still holds the content of testTranscript
+func checkUnchanged(t *testing.T, got *youtube.TranscriptResponse) {
+	t.Helper()
+	want := testTranscript()
+	for j, segment := range got.Raw.Segments {
+		if segment.Text != want.Raw.Segments[j].Text {
+			t.Errorf("segment %d = %q, want %q", j, segment.Text, want.Raw.Segments[j].Text)
+		}
+	}
+	for j, line := range got.Formatted {
+		if line != want.Formatted[j] {
+			t.Errorf("formatted line %d = %q, want %q", j, line, want.Formatted[j])
+		}
+	}
+	for j, chapter := range got.Chapters {
+		if chapter.Title != want.Chapters[j].Title {
+			t.Errorf("chapter %d = %q, want %q", j, chapter.Title, want.Chapters[j].Title)
+		}
+	}
+}
+
+// TestMemoryRepositoryCopies mutates the transcripts returned by Get and
+// GetOrFetch while other goroutines read them. Run with -race, which reports
+// any memory shared between the cache and its callers.
+func TestMemoryRepositoryCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	saved := testTranscript()
+	if err := repo.Save(ctx, "saved", saved); err != nil {
+		t.Fatal(err)
+	}
+	mutate(saved, -1)
+
+	fetch := func(context.Context) (*youtube.TranscriptResponse, error) {
+		// Keep the fetch in flight so that callers share its result
+		time.Sleep(10 * time.Millisecond)
+		return testTranscript(), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			got, err := repo.Get(ctx, "saved")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			checkUnchanged(t, got)
+			mutate(got, i)
+		}()
+		go func() {
+			defer wg.Done()
+			got, _, err := repo.GetOrFetch(ctx, "fetched", fetch)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			checkUnchanged(t, got)
+			mutate(got, i)
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range []string{"saved", "fetched"} {
+		got, err := repo.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkUnchanged(t, got)
+	}
+}
+
+// TestStaleRefreshBackoff serves a stale transcript while upstream fails and
+// checks that it is refreshed once, not on every request
+func TestStaleRefreshBackoff(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	repo.SetTTL(time.Millisecond, true)
+
+	var mu sync.Mutex
+	calls := 0
+	fetch := func(context.Context) (*youtube.TranscriptResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls > 1 {
+			return nil, fmt.Errorf("upstream down")
+		}
+		return testTranscript(), nil
+	}
+	if _, _, err := repo.GetOrFetch(ctx, "video", fetch); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	for range 20 {
+		_, status, err := repo.GetOrFetch(ctx, "video", fetch)
+		if err != nil || status != CacheStale {
+			t.Fatalf("GetOrFetch = %q, %v, want a stale transcript", status, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("fetched %d times, want once and one refresh", calls)
+	}
+}
+
+func TestMemoryRepositoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	size := approxSize(testTranscript())
+	repo.SetMaxBytes(3 * size)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repo.Save(ctx, id, testTranscript()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading a makes b the least recently used
+	if _, err := repo.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(ctx, "d", testTranscript()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := repo.Keys(), []string{"a", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	if repo.Bytes() != 3*size {
+		t.Errorf("bytes = %d, want %d", repo.Bytes(), 3*size)
+	}
+}
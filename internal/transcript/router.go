@@ -1,41 +1,105 @@
 package transcript
 
 import (
-	"embed"
+	"cmp"
 	"encoding/json"
+	"errors"
+	"io"
 	"io/fs"
 	"log/slog"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/static"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
 )
 
+// MaxUploadBytes limits the size of uploaded subtitle files
+const MaxUploadBytes = 10 << 20
+
 type Router struct {
 	service *Service
 }
 
-func NewRouter(svc *Service, uiAssets embed.FS) *http.ServeMux {
+// NewRouter registers the API routes and, when ui is not nil, serves the web
+// UI at "/". Without a UI, "/" describes the API instead.
+func NewRouter(svc *Service, ui fs.FS) *http.ServeMux {
 	r := &Router{service: svc}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/v1/transcripts", r.handleGetTranscripts)
+	mux.HandleFunc("/api/v1/transcripts/upload", r.handleUploadTranscript)
+	mux.HandleFunc("/api/v1/videos/{id}/status", r.handleVideoStatus)
+	mux.HandleFunc("/api/v1/stats/popular", r.handlePopular)
+	mux.HandleFunc("/api/v1/videos/{id}/html", r.handleVideoHTML)
+	mux.HandleFunc("/api/v1/videos/{id}/tokens", r.handleVideoTokens)
+	mux.HandleFunc("/api/v1/videos/{id}/highlights", r.handleVideoHighlights)
+	mux.HandleFunc("/api/v1/videos/{id}/captions/exists", r.handleCaptionsExist)
+	mux.HandleFunc("/api/v1/videos/{id}/captions/{lang}/raw", r.handleRawCaptions)
+	mux.HandleFunc("/api/v1/ext/summary", r.handleExtSummary)
+	mux.HandleFunc("/api/v1/jobs/{id}", r.handleJob)
 
-	// Serve static files from the dist directory
-	distFS, err := fs.Sub(uiAssets, "dist")
-	if err != nil {
-		panic(err)
+	if ui != nil {
+		mux.Handle("/", static.NewHandler(ui))
+	} else {
+		mux.HandleFunc("/", r.handleAPIInfo)
 	}
-	fs := http.FileServer(http.FS(distFS))
-	mux.Handle("/", fs)
 
 	return mux
 }
 
-func (r *Router) writeJSONError(w http.ResponseWriter, errMsg string, statusCode int) {
+// writeJSONError responds with the message for key in the language requested
+// by req.
+func (r *Router) writeJSONError(w http.ResponseWriter, req *http.Request, key i18n.Key, statusCode int) {
+	WriteError(w, req, key, statusCode)
+}
+
+// writeRequestError responds with 400, listing the invalid fields in the
+// client's language when err is a *ValidationError.
+func (r *Router) writeRequestError(w http.ResponseWriter, req *http.Request, err error) {
+	WriteRequestError(w, req, err)
+}
+
+// WriteError responds with the message for key, formatted with args, in the
+// language requested by req. Handlers outside this package use it to answer
+// in the same shape as the API.
+func WriteError(w http.ResponseWriter, req *http.Request, key i18n.Key, statusCode int, args ...any) {
+	lang := i18n.FromRequest(req)
+	w.Header().Set("Content-Language", lang)
+	writeErrorResponse(w, ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: i18n.Tf(lang, key, args...),
+	}, statusCode)
+}
+
+// WriteRequestError responds with 400, listing the invalid fields in the
+// client's language when err is a *ValidationError.
+func WriteRequestError(w http.ResponseWriter, req *http.Request, err error) {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		slog.Debug("Invalid request", "path", req.URL.Path, "error", err)
+		WriteError(w, req, i18n.InvalidParameters, http.StatusBadRequest)
+		return
+	}
+
+	lang := i18n.FromRequest(req)
+	w.Header().Set("Content-Language", lang)
+	writeErrorResponse(w, ErrorResponse{
+		Error:   http.StatusText(http.StatusBadRequest),
+		Message: i18n.T(lang, i18n.InvalidParameters),
+		Fields:  verr.localized(lang),
+	}, http.StatusBadRequest)
+}
+
+func writeErrorResponse(w http.ResponseWriter, body ErrorResponse, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	err := json.NewEncoder(w).Encode(ErrorResponse{
-		Error:   http.StatusText(statusCode),
-		Message: errMsg,
-	})
+	err := json.NewEncoder(w).Encode(body)
 	if err != nil {
 		slog.Error("Failed to encode error response", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -44,46 +108,718 @@ func (r *Router) writeJSONError(w http.ResponseWriter, errMsg string, statusCode
 
 func (r *Router) handleGetTranscripts(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
-		r.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
 		return
 	}
 
-	videoURL := req.URL.Query().Get("videoUrl")
-	if videoURL == "" {
-		r.writeJSONError(w, "Missing videoUrl parameter", http.StatusBadRequest)
+	start := time.Now()
+	query := newTranscriptQuery(req.URL.Query())
+	svcReq, err := query.Bind()
+	if err != nil {
+		r.service.recordRequest(req, svcReq, TranscriptResponse{}, err, time.Since(start))
+		r.writeRequestError(w, req, err)
 		return
 	}
+	exporter, isExport := format.Lookup(query.Format)
+	svcReq.Account = r.service.isAdmin(req)
 
-	intervalStr := req.URL.Query().Get("interval")
-	interval, err := strconv.ParseFloat(intervalStr, 64)
-	if err != nil {
-		interval = 0 // Will default to 10.0 in service
+	// Clients preferring an asynchronous response get a job instead of
+	// waiting for queued upstream requests
+	if prefersAsync(req) && !isExport && len(svcReq.Languages) == 0 {
+		key := req.Header.Get("Idempotency-Key")
+		if len(key) > MaxIdempotencyKeyLength {
+			r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("Idempotency-Key", i18n.TooLong, MaxIdempotencyKeyLength)}})
+			return
+		}
+		job, queued, err := r.service.SubmitIfQueued(req.Context(), svcReq, key, func(resp TranscriptResponse, err error) {
+			r.service.recordRequest(req, svcReq, resp, err, time.Since(start))
+		})
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			r.writeJSONError(w, req, i18n.IdempotencyKeyReused, http.StatusUnprocessableEntity)
+			return
+		}
+		if queued {
+			r.writeJob(w, req, job, http.StatusAccepted)
+			return
+		}
 	}
 
-	svcReq := TranscriptRequest{
-		VideoURL:        videoURL,
-		IntervalSeconds: interval,
+	if len(svcReq.Languages) > 0 {
+		multi, err := r.service.GetTranscriptsMulti(req.Context(), svcReq, svcReq.Languages)
+		r.service.recordRequest(req, svcReq, TranscriptResponse{
+			VideoID:  multi.VideoID,
+			Title:    multi.Title,
+			Language: strings.Join(svcReq.Languages, ","),
+		}, err, time.Since(start))
+		if err != nil {
+			r.writeTranscriptError(w, req, err)
+			return
+		}
+		if isExport {
+			r.writeBilingualExport(w, req, exporter, multi, svcReq)
+			return
+		}
+		r.localizeErrors(w, req, &multi)
+		r.writeJSON(w, multi, http.StatusOK)
+		return
 	}
 
 	resp, err := r.service.GetTranscripts(req.Context(), svcReq)
+	r.service.recordRequest(req, svcReq, resp, err, time.Since(start))
+	if err != nil {
+		r.writeTranscriptError(w, req, err)
+		return
+	}
+
+	if resp.Raw == nil && resp.Formatted == nil {
+		r.writeJSONError(w, req, i18n.NoTranscript, http.StatusNotFound)
+		return
+	}
+
+	if isExport {
+		r.writeExport(w, exporter, resp, svcReq)
+		return
+	}
+
+	r.writeTimingHeaders(w, resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		r.writeJSONError(w, req, i18n.EncodeFailed, http.StatusInternalServerError)
+	}
+}
+
+// writeTranscriptError maps GetTranscripts errors to responses
+func (r *Router) writeTranscriptError(w http.ResponseWriter, req *http.Request, err error) {
+	key, statusCode := transcriptErrorKey(err)
+	r.writeJSONError(w, req, key, statusCode)
+}
+
+// localizeErrors describes the languages of multi that failed with the same
+// messages as single language requests, never the upstream error, which may
+// contain request URLs and keys.
+func (r *Router) localizeErrors(w http.ResponseWriter, req *http.Request, multi *MultiTranscriptResponse) {
+	if len(multi.errs) == 0 {
+		return
+	}
+	lang := i18n.FromRequest(req)
+	w.Header().Set("Content-Language", lang)
+	multi.Errors = make(map[string]string, len(multi.errs))
+	for code, err := range multi.errs {
+		key, _ := transcriptErrorKey(err)
+		multi.Errors[code] = i18n.T(lang, key)
+	}
+}
+
+// transcriptErrorKey returns the message and status for a GetTranscripts
+// error
+func transcriptErrorKey(err error) (i18n.Key, int) {
+	switch {
+	case err == ErrInvalidURL:
+		return i18n.InvalidVideoURL, http.StatusBadRequest
+	case errors.Is(err, ErrInvalidInterval):
+		return i18n.InvalidInterval, http.StatusBadRequest
+	case errors.Is(err, ErrNoTranscript):
+		return i18n.NoTranscript, http.StatusNotFound
+	default:
+		return i18n.InternalError, http.StatusInternalServerError
+	}
+}
+
+// prefersAsync reports whether req asks for a 202 response instead of
+// waiting, with "Prefer: respond-async" (RFC 7240)
+func prefersAsync(req *http.Request) bool {
+	for _, header := range req.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(preference), ";")
+			if strings.EqualFold(strings.TrimSpace(name), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleJob serves the state of a background transcript request, and its
+// result once done
+func (r *Router) handleJob(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, ok := r.service.Job(req.PathValue("id"))
+	if !ok {
+		r.writeJSONError(w, req, i18n.NotFound, http.StatusNotFound)
+		return
+	}
+	r.writeJob(w, req, job, http.StatusOK)
+}
+
+// writeJob responds with job, pointing clients at where to poll it while it
+// is pending
+func (r *Router) writeJob(w http.ResponseWriter, req *http.Request, job Job, statusCode int) {
+	if job.Status == JobPending {
+		w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+		w.Header().Set("Retry-After", strconv.FormatInt(max(1, (job.EstimatedWaitMs+999)/1000), 10))
+	}
+	if statusCode == http.StatusAccepted {
+		w.Header().Set("Preference-Applied", "respond-async")
+	}
+	if job.err != nil {
+		key, _ := transcriptErrorKey(job.err)
+		lang := i18n.FromRequest(req)
+		w.Header().Set("Content-Language", lang)
+		job.Error = i18n.T(lang, key)
+	}
+	r.writeJSON(w, job, statusCode)
+}
+
+func (r *Router) handleUploadTranscript(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, MaxUploadBytes)
+	if err := req.ParseMultipartForm(MaxUploadBytes); err != nil {
+		r.writeJSONError(w, req, i18n.InvalidUpload, http.StatusBadRequest)
+		return
+	}
+
+	form := UploadForm{
+		VideoID:  req.FormValue("videoId"),
+		Title:    req.FormValue("title"),
+		Format:   req.FormValue("format"),
+		Language: req.FormValue("language"),
+	}
+	file, header, err := req.FormFile("file")
+	if err == nil {
+		defer file.Close()
+		form.HasFile = true
+		form.FileName = header.Filename
+	}
+	if err := form.Validate(); err != nil {
+		r.writeRequestError(w, req, err)
+		return
+	}
+	// Uploads bound to a video replace what every user gets for it
+	bind := r.service.isAdmin(req)
+	if form.VideoID != "" && !bind {
+		r.writeJSONError(w, req, i18n.UploadBindingForbidden, http.StatusForbidden)
+		return
+	}
+
+	// An explicit format overrides the file extension
+	name := form.FileName
+	if form.Format != "" {
+		name = form.Format
+	}
+	segments, err := format.Parse(name, file)
+	if err != nil {
+		r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("file", i18n.Unreadable, err)}})
+		return
+	}
+
+	// A yt-dlp info.json supplies the video ID and metadata
+	var videoID string
+	if infoFile, _, infoErr := req.FormFile("info"); infoErr == nil {
+		defer infoFile.Close()
+		info, err := ParseYtDlpInfo(infoFile)
+		if err != nil {
+			r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("info", i18n.Unreadable, err)}})
+			return
+		}
+		if form.VideoID != "" && form.VideoID != info.ID {
+			r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("videoId", i18n.IDMismatch)}})
+			return
+		}
+		// Anonymous uploads keep the metadata but not the video ID
+		if !bind {
+			info.ID = contentID(segments)
+		}
+		info.Title = cmp.Or(form.Title, info.Title)
+		lang := cmp.Or(form.Language, subtitleLanguage(form.FileName), info.Language)
+		if lang != "" {
+			videoID, err = info.ID, r.service.IngestYtDlp(req.Context(), info, map[string][]youtube.TranscriptSegment{lang: segments})
+		} else {
+			// Without a language the file is stored like a plain upload
+			videoID, err = r.service.Ingest(req.Context(), info.ID, info.Title, segments)
+		}
+	} else {
+		videoID, err = r.service.Ingest(req.Context(), form.VideoID, form.Title, segments)
+	}
 	if err != nil {
 		switch {
-		case err == ErrInvalidURL:
-			r.writeJSONError(w, "Invalid YouTube video URL", http.StatusBadRequest)
+		case errors.Is(err, ErrNoTranscript):
+			r.writeJSONError(w, req, i18n.EmptySubtitleFile, http.StatusBadRequest)
 		default:
-			r.writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+			r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
 		}
 		return
 	}
 
-	if resp.Raw == nil && resp.Formatted == nil {
-		r.writeJSONError(w, "No transcript available", http.StatusNotFound)
+	resp, err := r.service.GetTranscripts(req.Context(), TranscriptRequest{VideoID: videoID})
+	if err != nil {
+		r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		return
+	}
+
+	r.writeJSON(w, resp, http.StatusCreated)
+}
+
+func (r *Router) handleAPIInfo(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		r.writeJSONError(w, req, i18n.NotFound, http.StatusNotFound)
+		return
+	}
+
+	r.writeJSON(w, APIInfo{
+		Name: "YouTube Video Summary API",
+		Endpoints: []string{
+			"GET /api/v1/transcripts",
+			"POST /api/v1/transcripts/upload",
+			"GET /api/v1/videos/{id}/status",
+			"GET /api/v1/stats/popular",
+			"GET /api/v1/videos/{id}/html",
+			"GET /api/v1/videos/{id}/tokens",
+			"GET /api/v1/videos/{id}/highlights",
+			"GET /api/v1/videos/{id}/captions/exists",
+			"GET /api/v1/videos/{id}/captions/{lang}/raw",
+			"GET /api/v1/ext/summary",
+			"GET /api/v1/jobs/{id}",
+		},
+	}, http.StatusOK)
+}
+
+func (r *Router) handleVideoStatus(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID := req.PathValue("id")
+	if !videoIDPattern.MatchString(videoID) {
+		r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("id", i18n.InvalidVideoID)}})
+		return
+	}
+
+	status, err := r.service.Status(req.Context(), videoID)
+	if err != nil {
+		r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		return
+	}
+
+	r.writeJSON(w, status, http.StatusOK)
+}
+
+// handlePopular lists the videos most requested within window, e.g. 24h, for
+// a "what others are summarizing" feed.
+func (r *Router) handlePopular(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	var v validator
+	window := DefaultPopularWindow
+	if value := query.Get("window"); value != "" {
+		d, err := time.ParseDuration(value)
+		v.check(err == nil && d > 0 && d <= MaxPopularWindow, "window", i18n.InvalidWindow, MaxPopularWindow)
+		window = d
+	}
+	limit := DefaultPopularLimit
+	if value := query.Get("limit"); value != "" {
+		limit = v.int("limit", value, 1, MaxPopularLimit)
+	}
+	if err := v.err(); err != nil {
+		r.writeRequestError(w, req, err)
+		return
+	}
+
+	popular, err := r.service.Popular(req.Context(), window, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotSupported):
+			r.writeJSONError(w, req, i18n.NotFound, http.StatusNotFound)
+		default:
+			r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	r.writeJSON(w, popular, http.StatusOK)
+}
+
+// handleVideoHTML renders the transcript as a standalone printable page,
+// fetching it first when it is not cached.
+func (r *Router) handleVideoHTML(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID := req.PathValue("id")
+	if !videoIDPattern.MatchString(videoID) {
+		r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("id", i18n.InvalidVideoID)}})
 		return
 	}
 
+	resp, err := r.service.GetTranscripts(req.Context(), TranscriptRequest{VideoID: videoID})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoTranscript):
+			r.writeJSONError(w, req, i18n.NoTranscript, http.StatusNotFound)
+		default:
+			r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var segments []youtube.TranscriptSegment
+	if resp.Raw != nil {
+		segments = resp.Raw.Segments
+	}
+	renderStart := time.Now()
+	body := format.HTML(segments, format.Options{
+		Title:           resp.Title,
+		IntervalSeconds: DefaultIntervalSeconds,
+		VideoID:         resp.VideoID,
+		Language:        resp.Language,
+	})
+	resp.Timing.Format += time.Since(renderStart)
+
+	r.writeTimingHeaders(w, resp)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, body); err != nil {
+		slog.Error("Failed to write HTML page", "video_id", videoID, "error", err)
+	}
+}
+
+// handleVideoTokens estimates how many tokens the cleaned transcript takes up
+// for the tokenizer of the requested model.
+func (r *Router) handleVideoTokens(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID := req.PathValue("id")
+	model := req.URL.Query().Get("model")
+	if model == "" {
+		model = format.DefaultTokenModel
+	}
+	tokenizer, ok := format.TokenizerFor(model)
+
+	var v validator
+	v.check(videoIDPattern.MatchString(videoID), "id", i18n.InvalidVideoID)
+	v.check(ok, "model", i18n.UnsupportedModel, model, strings.Join(format.TokenModels(), ", "))
+	if err := v.err(); err != nil {
+		r.writeRequestError(w, req, err)
+		return
+	}
+
+	resp, err := r.service.GetTranscripts(req.Context(), TranscriptRequest{
+		VideoID: videoID,
+		Clean:   format.CleanOptions{SoundTags: true, Fillers: true},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoTranscript):
+			r.writeJSONError(w, req, i18n.NoTranscript, http.StatusNotFound)
+		default:
+			r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var segments []youtube.TranscriptSegment
+	if resp.Raw != nil {
+		segments = resp.Raw.Segments
+	}
+	w.Header().Set("X-Cache", string(resp.Cache))
+	r.writeJSON(w, TokenCount{
+		VideoID:       videoID,
+		Model:         model,
+		Tokenizer:     tokenizer.Name,
+		Estimated:     true,
+		TokenEstimate: format.EstimateTokens(segments, tokenizer),
+	}, http.StatusOK)
+}
+
+// Bounds of the highlights endpoint parameters
+const (
+	defaultHighlightCount    = 5
+	maxHighlightCount        = 20
+	defaultHighlightDuration = 30
+	minHighlightDuration     = 5
+	maxHighlightDuration     = 300
+)
+
+// handleVideoHighlights suggests the count moments of about duration seconds
+// densest in salient words, as start and end ranges to clip.
+func (r *Router) handleVideoHighlights(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID := req.PathValue("id")
+	query := req.URL.Query()
+	lang := query.Get("lang")
+
+	var v validator
+	v.check(videoIDPattern.MatchString(videoID), "id", i18n.InvalidVideoID)
+	v.check(lang == "" || languagePattern.MatchString(lang), "lang", i18n.InvalidLanguage)
+	count := v.float("count", query.Get("count"), 1, maxHighlightCount)
+	v.check(count == math.Trunc(count), "count", i18n.WholeNumber)
+	duration := v.float("duration", query.Get("duration"), minHighlightDuration, maxHighlightDuration)
+	if err := v.err(); err != nil {
+		r.writeRequestError(w, req, err)
+		return
+	}
+	if count == 0 {
+		count = defaultHighlightCount
+	}
+	if duration == 0 {
+		duration = defaultHighlightDuration
+	}
+
+	resp, err := r.service.GetTranscripts(req.Context(), TranscriptRequest{
+		VideoID:  videoID,
+		Language: lang,
+		Clean:    format.CleanOptions{SoundTags: true, Fillers: true},
+	})
+	if err != nil {
+		r.writeTranscriptError(w, req, err)
+		return
+	}
+	if resp.Raw == nil {
+		r.writeJSONError(w, req, i18n.NoTranscript, http.StatusNotFound)
+		return
+	}
+
+	highlights := format.Highlights(resp.Raw.Segments, int(count), duration)
+	if highlights == nil {
+		highlights = []format.Highlight{}
+	}
+	w.Header().Set("X-Cache", string(resp.Cache))
+	r.writeJSON(w, VideoHighlights{
+		VideoID:    resp.VideoID,
+		Title:      resp.Title,
+		Language:   resp.Language,
+		Highlights: highlights,
+	}, http.StatusOK)
+}
+
+// handleExtSummary serves a cleaned transcript of video v in the short
+// ExtSummary shape for browser extensions, with single letter keys when
+// compact is true.
+func (r *Router) handleExtSummary(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	videoID, lang, compact := query.Get("v"), query.Get("lang"), query.Get("compact")
+
+	var v validator
+	v.check(videoIDPattern.MatchString(videoID), "v", i18n.InvalidVideoID)
+	v.check(lang == "" || languagePattern.MatchString(lang), "lang", i18n.InvalidLanguage)
+	v.check(compact == "" || compact == "true" || compact == "false", "compact", i18n.NotBoolean)
+	if err := v.err(); err != nil {
+		r.writeRequestError(w, req, err)
+		return
+	}
+
+	resp, err := r.service.GetTranscripts(req.Context(), TranscriptRequest{
+		VideoID:  videoID,
+		Language: lang,
+		Clean:    format.CleanOptions{SoundTags: true, Fillers: true},
+	})
+	if err != nil {
+		r.writeTranscriptError(w, req, err)
+		return
+	}
+	if resp.Raw == nil {
+		r.writeJSONError(w, req, i18n.NoTranscript, http.StatusNotFound)
+		return
+	}
+
+	summary := ExtSummary{
+		VideoID:  resp.VideoID,
+		Title:    resp.Title,
+		Language: resp.Language,
+		Lines:    resp.Formatted,
+	}
+	if n := len(resp.Raw.Segments); n > 0 {
+		last := resp.Raw.Segments[n-1]
+		summary.Duration = last.StartTime + last.Duration
+	}
+	if summary.Lines == nil {
+		summary.Lines = []string{}
+	}
+
+	w.Header().Set("X-Cache", string(resp.Cache))
+	if compact == "true" {
+		r.writeJSON(w, compactExtSummary(summary), http.StatusOK)
+		return
+	}
+	r.writeJSON(w, summary, http.StatusOK)
+}
+
+// handleCaptionsExist reports the available caption languages from the player
+// response only, for clients that want to know early whether a transcript can
+// be fetched.
+func (r *Router) handleCaptionsExist(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID := req.PathValue("id")
+	if !videoIDPattern.MatchString(videoID) {
+		r.writeRequestError(w, req, &ValidationError{Fields: []FieldError{NewFieldError("id", i18n.InvalidVideoID)}})
+		return
+	}
+
+	availability, err := r.service.CaptionAvailability(req.Context(), videoID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotSupported):
+			r.writeJSONError(w, req, i18n.NotSupported, http.StatusNotImplemented)
+		default:
+			r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	r.writeJSON(w, availability, http.StatusOK)
+}
+
+// handleRawCaptions proxies a caption track unchanged in the format requested
+// with fmt, ttml by default.
+func (r *Router) handleRawCaptions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		r.writeJSONError(w, req, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	videoID := req.PathValue("id")
+	lang := req.PathValue("lang")
+	captionFormat := req.URL.Query().Get("fmt")
+	if captionFormat == "" {
+		captionFormat = "ttml"
+	}
+
+	var v validator
+	v.check(videoIDPattern.MatchString(videoID), "id", i18n.InvalidVideoID)
+	v.check(languagePattern.MatchString(lang), "lang", i18n.InvalidLanguage)
+	v.check(slices.Contains(youtube.RawCaptionFormats(), captionFormat),
+		"fmt", i18n.OneOf, strings.Join(youtube.RawCaptionFormats(), ", "))
+	if err := v.err(); err != nil {
+		r.writeRequestError(w, req, err)
+		return
+	}
+
+	raw, err := r.service.RawCaptions(req.Context(), videoID, lang, captionFormat)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoTranscript):
+			r.writeJSONError(w, req, i18n.NoCaptionTrack, http.StatusNotFound)
+		case errors.Is(err, ErrNotSupported):
+			r.writeJSONError(w, req, i18n.NotSupported, http.StatusNotImplemented)
+		default:
+			r.writeJSONError(w, req, i18n.InternalError, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", raw.ContentType)
+	w.Header().Set("Content-Language", raw.LanguageCode)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(raw.Data); err != nil {
+		slog.Error("Failed to write raw captions", "video_id", videoID, "error", err)
+	}
+}
+
+// writeBilingualExport interleaves the first requested language with the
+// second, aligned by timestamps.
+func (r *Router) writeBilingualExport(w http.ResponseWriter, req *http.Request, exporter format.Exporter, multi MultiTranscriptResponse, svcReq TranscriptRequest) {
+	primary, ok := multi.Transcripts[svcReq.Languages[0]]
+	secondary, ok2 := multi.Transcripts[svcReq.Languages[1]]
+	if !ok || !ok2 || primary.Raw == nil || secondary.Raw == nil {
+		r.writeJSONError(w, req, i18n.NoCaptionTrack, http.StatusNotFound)
+		return
+	}
+
+	segments := format.Interleave(primary.Raw.Segments, secondary.Raw.Segments)
+	body, ok := format.RenderBilingual(exporter.Name, segments, format.Options{
+		Title:         multi.Title,
+		OffsetSeconds: svcReq.OffsetSeconds,
+		TimeScale:     svcReq.TimeScale,
+	})
+	if !ok {
+		var v validator
+		v.check(false, "format", i18n.InterleaveFormats, strings.Join(format.BilingualFormats, ", "))
+		r.writeRequestError(w, req, v.err())
+		return
+	}
+
+	w.Header().Set("Content-Type", exporter.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, body); err != nil {
+		slog.Error("Failed to write export", "format", exporter.Name, "error", err)
+	}
+}
+
+// writeTimingHeaders reports cache status and where the request spent its
+// time, in milliseconds.
+func (r *Router) writeTimingHeaders(w http.ResponseWriter, resp TranscriptResponse) {
+	w.Header().Set("X-Cache", string(resp.Cache))
+	w.Header().Set("X-Upstream-Time", strconv.FormatFloat(milliseconds(resp.Timing.Upstream), 'f', 3, 64))
+	w.Header().Set("X-Format-Time", strconv.FormatFloat(milliseconds(resp.Timing.Format), 'f', 3, 64))
+}
+
+func (r *Router) writeJSON(w http.ResponseWriter, body any, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
+
+func (r *Router) writeExport(w http.ResponseWriter, exporter format.Exporter, resp TranscriptResponse, svcReq TranscriptRequest) {
+	interval := svcReq.IntervalSeconds
+	if interval == 0 {
+		interval = DefaultIntervalSeconds
+	}
+
+	var segments []youtube.TranscriptSegment
+	if resp.Raw != nil {
+		segments = resp.Raw.Segments
+	}
+
+	renderStart := time.Now()
+	body := exporter.Render(segments, format.Options{
+		Title:           resp.Title,
+		IntervalSeconds: interval,
+		OffsetSeconds:   svcReq.OffsetSeconds,
+		TimeScale:       svcReq.TimeScale,
+		Cues:            svcReq.Cues,
+		VideoID:         resp.VideoID,
+		Language:        resp.Language,
+		Metadata:        svcReq.Metadata,
+	})
+	resp.Timing.Format += time.Since(renderStart)
+
+	r.writeTimingHeaders(w, resp)
+	w.Header().Set("Content-Type", exporter.ContentType)
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		r.writeJSONError(w, "Failed to encode response", http.StatusInternalServerError)
+	if _, err := io.WriteString(w, body); err != nil {
+		slog.Error("Failed to write export", "format", exporter.Name, "error", err)
 	}
 }
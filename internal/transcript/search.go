@@ -0,0 +1,455 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+const (
+	// DefaultSearchIndex is the index name used when none is configured
+	DefaultSearchIndex = "transcripts"
+	// searchTimeout bounds a single request to the search cluster
+	searchTimeout = 30 * time.Second
+	// backfillBatch is the number of documents per bulk request
+	backfillBatch = 100
+	// searchWriters bounds the documents indexed concurrently, searchBacklog
+	// the documents waiting for or being indexed. Documents beyond the backlog
+	// are skipped until a backfill.
+	searchWriters = 4
+	searchBacklog = 64
+	// setupBackoff is the wait before retrying a failed index setup, doubled
+	// on every failure up to maxSetupBackoff
+	setupBackoff    = time.Second
+	maxSetupBackoff = 5 * time.Minute
+)
+
+// searchMapping is applied when the index is created and merged into the
+// mapping of an existing index. Segments are nested so that a query can
+// match the words of one segment and return its start time.
+const searchMapping = `{
+  "properties": {
+    "videoId": {"type": "keyword"},
+    "title": {"type": "text", "fields": {"raw": {"type": "keyword", "ignore_above": 256}}},
+    "channel": {"type": "text", "fields": {"raw": {"type": "keyword", "ignore_above": 256}}},
+    "language": {"type": "keyword"},
+    "source": {"type": "keyword"},
+    "url": {"type": "keyword", "index": false},
+    "indexedAt": {"type": "date"},
+    "duration": {"type": "float"},
+    "text": {"type": "text"},
+    "segments": {
+      "type": "nested",
+      "properties": {
+        "text": {"type": "text"},
+        "start": {"type": "float"},
+        "duration": {"type": "float"}
+      }
+    }
+  }
+}`
+
+// searchIndex ships transcripts to an Elasticsearch or OpenSearch index over
+// its REST API
+type searchIndex struct {
+	client  *http.Client
+	baseURL string
+	index   string
+	// auth is the Authorization header value, if any
+	auth string
+
+	// writes and backlog are semaphores bounding concurrent writes and the
+	// goroutines waiting for one
+	writes  chan struct{}
+	backlog chan struct{}
+
+	// mu guards the setup state below. It is never held during requests.
+	mu    sync.Mutex
+	ready bool
+	// setup is closed when the running setup attempt ends, nil when none runs
+	setup chan struct{}
+	// setupErr is the error of the last failed attempt, retried after retryAt
+	setupErr error
+	retryAt  time.Time
+	backoff  time.Duration
+}
+
+// searchDocument is the indexed form of a transcript
+type searchDocument struct {
+	VideoID   string          `json:"videoId"`
+	Title     string          `json:"title,omitempty"`
+	Channel   string          `json:"channel,omitempty"`
+	Language  string          `json:"language,omitempty"`
+	Source    string          `json:"source,omitempty"`
+	URL       string          `json:"url,omitempty"`
+	IndexedAt time.Time       `json:"indexedAt"`
+	Duration  float64         `json:"duration"`
+	Text      string          `json:"text"`
+	Segments  []searchSegment `json:"segments"`
+}
+
+type searchSegment struct {
+	Text     string  `json:"text"`
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+}
+
+// BackfillResult counts the transcripts sent by Backfill
+type BackfillResult struct {
+	Indexed int `json:"indexed"`
+	Failed  int `json:"failed"`
+}
+
+// SetSearchIndex indexes every transcript fetched or ingested from now on
+// into index of the Elasticsearch or OpenSearch cluster at rawURL. Basic auth
+// credentials may be part of the URL, an API key is sent instead when set.
+// The index and its mapping are created on first use. An empty rawURL
+// disables indexing.
+func (s *Service) SetSearchIndex(rawURL, index, apiKey string) error {
+	if rawURL == "" {
+		s.search = nil
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid search url %q", rawURL)
+	}
+	if index == "" {
+		index = DefaultSearchIndex
+	}
+	if index != strings.ToLower(index) || strings.ContainsAny(index, `/\*?"<>| ,#`) {
+		return fmt.Errorf("invalid search index name %q", index)
+	}
+
+	idx := &searchIndex{
+		client:  &http.Client{Timeout: searchTimeout},
+		index:   index,
+		writes:  make(chan struct{}, searchWriters),
+		backlog: make(chan struct{}, searchBacklog),
+	}
+	switch {
+	case apiKey != "":
+		idx.auth = "ApiKey " + apiKey
+	case u.User != nil:
+		password, _ := u.User.Password()
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(u.User.Username(), password)
+		idx.auth = req.Header.Get("Authorization")
+	}
+	u.User = nil
+	idx.baseURL = strings.TrimRight(u.String(), "/")
+
+	s.search = idx
+	return nil
+}
+
+// do sends a request to the cluster and returns the response body, treating
+// any status other than 2xx as an error.
+func (idx *searchIndex) do(ctx context.Context, method, path, contentType string, body []byte) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, idx.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if idx.auth != "" {
+		req.Header.Set("Authorization", idx.auth)
+	}
+
+	resp, err := idx.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return respBody, resp.StatusCode, fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, respBody[:min(len(respBody), 300)])
+	}
+	return respBody, resp.StatusCode, nil
+}
+
+// ensureIndex creates the index with searchMapping, or updates the mapping
+// of an existing index, once per process. Concurrent callers wait for the
+// running attempt instead of starting their own. A failed attempt is retried
+// by the first call after its backoff; calls before then fail fast with its
+// error.
+func (idx *searchIndex) ensureIndex(ctx context.Context) error {
+	for {
+		idx.mu.Lock()
+		if idx.ready {
+			idx.mu.Unlock()
+			return nil
+		}
+		if running := idx.setup; running != nil {
+			idx.mu.Unlock()
+			select {
+			case <-running:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if time.Now().Before(idx.retryAt) {
+			err := idx.setupErr
+			idx.mu.Unlock()
+			return err
+		}
+		done := make(chan struct{})
+		idx.setup = done
+		idx.mu.Unlock()
+
+		err := idx.createIndex(ctx)
+
+		idx.mu.Lock()
+		idx.setup = nil
+		if err == nil {
+			idx.ready = true
+		} else {
+			idx.backoff = min(max(2*idx.backoff, setupBackoff), maxSetupBackoff)
+			idx.retryAt = time.Now().Add(idx.backoff)
+			idx.setupErr = err
+		}
+		idx.mu.Unlock()
+		close(done)
+		return err
+	}
+}
+
+// createIndex creates the index with searchMapping, or updates the mapping
+// of an existing index.
+func (idx *searchIndex) createIndex(ctx context.Context) error {
+	path := "/" + url.PathEscape(idx.index)
+	_, status, err := idx.do(ctx, http.MethodHead, path, "", nil)
+	switch {
+	case status == http.StatusNotFound:
+		body := []byte(`{"mappings":` + searchMapping + `}`)
+		if _, _, err := idx.do(ctx, http.MethodPut, path, "application/json", body); err != nil {
+			return fmt.Errorf("create search index: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("check search index: %w", err)
+	default:
+		if _, _, err := idx.do(ctx, http.MethodPut, path+"/_mapping", "application/json", []byte(searchMapping)); err != nil {
+			return fmt.Errorf("update search mapping: %w", err)
+		}
+	}
+	return nil
+}
+
+// newSearchDocument converts a transcript of videoID for indexing
+func newSearchDocument(videoID string, resp *youtube.TranscriptResponse) searchDocument {
+	doc := searchDocument{
+		VideoID:   videoID,
+		Title:     resp.Title,
+		Channel:   resp.Channel,
+		Language:  resp.Language,
+		Source:    resp.Source,
+		IndexedAt: time.Now().UTC(),
+		Segments:  []searchSegment{},
+	}
+	if resp.Source != "upload" {
+		doc.URL = "https://www.youtube.com/watch?v=" + videoID
+	}
+
+	var text strings.Builder
+	for _, segment := range resp.Raw.Segments {
+		doc.Segments = append(doc.Segments, searchSegment{Text: segment.Text, Start: segment.StartTime, Duration: segment.Duration})
+		if text.Len() > 0 {
+			text.WriteByte(' ')
+		}
+		text.WriteString(segment.Text)
+		doc.Duration = max(doc.Duration, segment.StartTime+segment.Duration)
+	}
+	doc.Text = text.String()
+	return doc
+}
+
+// documentID identifies a transcript by video and language, so that the
+// same transcript cached for several regions is indexed once
+func documentID(videoID, language string) string {
+	if language == "" {
+		return videoID
+	}
+	return videoID + ":" + language
+}
+
+// put indexes a transcript in the background. Failures are logged rather
+// than returned since indexing must not affect responses. At most
+// searchWriters documents are written at once; when searchBacklog documents
+// are already pending the transcript is skipped.
+func (idx *searchIndex) put(logger *slog.Logger, videoID string, resp *youtube.TranscriptResponse) {
+	if idx == nil || resp == nil || resp.Raw == nil {
+		return
+	}
+	select {
+	case idx.backlog <- struct{}{}:
+	default:
+		logger.Warn("Search indexing backlog full, skipping transcript", "video_id", videoID)
+		return
+	}
+	doc := newSearchDocument(videoID, resp)
+
+	go func() {
+		defer func() { <-idx.backlog }()
+		idx.writes <- struct{}{}
+		defer func() { <-idx.writes }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+		defer cancel()
+
+		if err := idx.ensureIndex(ctx); err != nil {
+			logger.Warn("Failed to prepare search index", "error", err)
+			return
+		}
+		body, err := json.Marshal(doc)
+		if err != nil {
+			logger.Warn("Failed to encode search document", "video_id", videoID, "error", err)
+			return
+		}
+		path := "/" + url.PathEscape(idx.index) + "/_doc/" + url.PathEscape(documentID(videoID, doc.Language))
+		if _, _, err := idx.do(ctx, http.MethodPut, path, "application/json", body); err != nil {
+			logger.Warn("Failed to index transcript", "video_id", videoID, "error", err)
+			return
+		}
+		logger.Debug("Indexed transcript", "video_id", videoID)
+	}()
+}
+
+// index indexes a transcript unless FeatureSearchIndex is switched off.
+func (s *Service) index(videoID string, resp *youtube.TranscriptResponse) {
+	if s.feature(FeatureSearchIndex, true) {
+		s.search.put(s.logger, videoID, resp)
+	}
+}
+
+// delete removes every document of videoID and returns how many were
+// removed.
+func (idx *searchIndex) delete(ctx context.Context, videoID string) (int, error) {
+	if idx == nil {
+		return 0, nil
+	}
+
+	query, err := json.Marshal(map[string]any{"query": map[string]any{"term": map[string]string{"videoId": videoID}}})
+	if err != nil {
+		return 0, err
+	}
+	body, status, err := idx.do(ctx, http.MethodPost, "/"+url.PathEscape(idx.index)+"/_delete_by_query?refresh=true", "application/json", query)
+	if status == http.StatusNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("delete search documents: %w", err)
+	}
+
+	var result struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("decode delete response: %w", err)
+	}
+	return result.Deleted, nil
+}
+
+// Backfill indexes every cached transcript with the bulk API, for
+// transcripts cached before indexing was enabled. ErrNotSupported is
+// returned when indexing is disabled.
+func (s *Service) Backfill(ctx context.Context) (BackfillResult, error) {
+	idx := s.search
+	if idx == nil {
+		return BackfillResult{}, ErrNotSupported
+	}
+	if err := idx.ensureIndex(ctx); err != nil {
+		return BackfillResult{}, err
+	}
+
+	var (
+		result BackfillResult
+		batch  bytes.Buffer
+		queued int
+	)
+	flush := func() error {
+		if queued == 0 {
+			return nil
+		}
+		defer func() {
+			batch.Reset()
+			queued = 0
+		}()
+
+		body, _, err := idx.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", batch.Bytes())
+		if err != nil {
+			return fmt.Errorf("bulk index: %w", err)
+		}
+		var resp struct {
+			Items []map[string]struct {
+				Status int `json:"status"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("decode bulk response: %w", err)
+		}
+		for _, item := range resp.Items {
+			for _, action := range item {
+				if action.Status >= 200 && action.Status <= 299 {
+					result.Indexed++
+				} else {
+					result.Failed++
+				}
+			}
+		}
+		return nil
+	}
+
+	// Keys of the same transcript for other regions map to one document
+	written := make(map[string]bool)
+	enc := json.NewEncoder(&batch)
+	for _, key := range s.repo.Keys() {
+		resp, err := s.repo.Get(ctx, key)
+		if err != nil || resp.Raw == nil {
+			continue
+		}
+		videoID, _, _ := strings.Cut(key, "#")
+		videoID, _, _ = strings.Cut(videoID, "@")
+		doc := newSearchDocument(videoID, resp)
+		id := documentID(videoID, doc.Language)
+		if written[id] {
+			continue
+		}
+		written[id] = true
+
+		action := map[string]any{"index": map[string]string{"_index": idx.index, "_id": id}}
+		if err := enc.Encode(action); err != nil {
+			return result, err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return result, err
+		}
+		if queued++; queued >= backfillBatch {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	s.logger.Info("Backfilled search index", "indexed", result.Indexed, "failed", result.Failed)
+	return result, nil
+}
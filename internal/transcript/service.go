@@ -2,101 +2,564 @@ package transcript
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
 	"net/url"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
 	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
 )
 
 var (
-	ErrNoTranscript   = errors.New("no transcript available")
-	ErrFailedToGet    = errors.New("failed to get transcript")
-	ErrFailedToFormat = errors.New("failed to format transcript")
-	ErrInvalidURL     = errors.New("invalid YouTube video URL")
+	ErrNoTranscript    = errors.New("no transcript available")
+	ErrFailedToGet     = errors.New("failed to get transcript")
+	ErrInvalidURL      = errors.New("invalid YouTube video URL")
+	ErrInvalidInterval = errors.New("invalid interval")
+	ErrNotSupported    = errors.New("not supported by the transcript source")
+	ErrInvalidClient   = errors.New("invalid client IP")
+)
+
+// Bounds for the grouping interval of formatted transcripts, in seconds.
+const (
+	DefaultIntervalSeconds = 10.0
+	MinIntervalSeconds     = 1.0
+	MaxIntervalSeconds     = 600.0
+)
+
+// Bounds for reducing transcripts to a token budget
+const (
+	MinTokenBudget = 100
+	MaxTokenBudget = 2_000_000
+)
+
+// Bounds for retiming subtitle exports
+const (
+	MaxOffsetSeconds = 86400.0
+	MinTimeScale     = 0.1
+	MaxTimeScale     = 10.0
+)
+
+// TranscriptFetcher retrieves transcripts from an upstream source. The
+// YouTube client is the default implementation.
+type TranscriptFetcher interface {
+	GetTranscript(ctx context.Context, videoID string, opts ...youtube.RequestOption) (*youtube.TranscriptResponse, error)
+}
+
+// RawCaptionFetcher is implemented by fetchers that can pass caption payloads
+// through unparsed, such as the YouTube client.
+type RawCaptionFetcher interface {
+	GetRawCaptions(ctx context.Context, videoID, lang, format string, opts ...youtube.RequestOption) (*youtube.RawCaptions, error)
+}
+
+// CaptionLister is implemented by fetchers that can list caption tracks
+// without downloading them, such as the YouTube client.
+type CaptionLister interface {
+	ListCaptionTracks(ctx context.Context, videoID string, opts ...youtube.RequestOption) ([]youtube.CaptionTrack, error)
+}
+
+var (
+	_ TranscriptFetcher = (*youtube.Client)(nil)
+	_ RawCaptionFetcher = (*youtube.Client)(nil)
+	_ CaptionLister     = (*youtube.Client)(nil)
 )
 
 type Service struct {
-	client *youtube.Client
-	repo   Repository
+	fetcher   TranscriptFetcher
+	repo      Repository
+	logger    *slog.Logger
+	shadow    *shadow
+	vault     *vault
+	metrics   *metrics
+	analytics *analytics
+	search    *searchIndex
+	features  Features
+	// timestampStyles maps lowercase languages, "" for any, to the style
+	// of formatted timestamps
+	timestampStyles map[string]format.TimestampStyle
+	// popularVisible publishes the most requested videos
+	popularVisible bool
+	// uploadToken authorizes storing uploads under YouTube video IDs
+	uploadToken string
+	jobs        *jobQueue
 }
 
-func NewService(client *youtube.Client, repo Repository) *Service {
+func NewService(fetcher TranscriptFetcher, repo Repository, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	return &Service{
-		client: client,
-		repo:   repo,
+		fetcher: fetcher,
+		repo:    repo,
+		logger:  logger,
+		metrics: newMetrics(),
+		jobs:    newJobQueue(),
 	}
 }
 
 func (s *Service) GetTranscripts(ctx context.Context, req TranscriptRequest) (TranscriptResponse, error) {
 	interval := req.IntervalSeconds
-	if interval <= 0 {
-		interval = 10.0
+	if interval == 0 {
+		interval = DefaultIntervalSeconds
 	}
-
-	// Validate video URL
-	if req.VideoURL == "" || !s.IsValidUrl(req.VideoURL) {
-		return TranscriptResponse{}, ErrInvalidURL
+	if interval < MinIntervalSeconds || interval > MaxIntervalSeconds {
+		return TranscriptResponse{}, ErrInvalidInterval
 	}
 
 	// Extract video ID from URL if not provided
 	if req.VideoID == "" {
+		// Validate video URL
+		if req.VideoURL == "" || !s.IsValidUrl(req.VideoURL) {
+			s.metrics.recordRejected(RejectInvalidURL)
+			return TranscriptResponse{}, ErrInvalidURL
+		}
+
 		req.VideoID = s.ExtractVideoId(req.VideoURL)
 		if req.VideoID == "" {
+			s.metrics.recordRejected(RejectInvalidURL)
 			return TranscriptResponse{}, ErrInvalidURL
 		}
 	}
 
-	var youtubeResp *youtube.TranscriptResponse
-	var err error
+	var startSeconds float64
+	if req.RespectStartTime {
+		startSeconds = StartTimeFromURL(req.VideoURL)
+	}
 
-	// Try to get from cache first
-	youtubeResp, err = s.repo.Get(ctx, req.VideoID)
-	if err != nil {
-		if !errors.Is(err, ErrTranscriptNotFound) {
-			s.client.Logger().Error("Failed to get transcript from repository", "video_id", req.VideoID, "error", err)
-		}
+	var fetchOpts []youtube.RequestOption
+	if req.Region != "" {
+		fetchOpts = append(fetchOpts, youtube.WithRegion(req.Region))
+	}
+	if req.UILanguage != "" {
+		fetchOpts = append(fetchOpts, youtube.WithUILanguage(req.UILanguage))
+	}
+	if req.Language != "" {
+		fetchOpts = append(fetchOpts, youtube.WithLanguage(req.Language))
+	}
+	if req.Confidence {
+		fetchOpts = append(fetchOpts, youtube.WithConfidence())
+	}
+	if req.Account {
+		fetchOpts = append(fetchOpts, youtube.WithAccount())
+	}
 
-		// If not in cache or error, fetch from YouTube
-		youtubeResp, err = s.client.GetTranscript(ctx, req.VideoID)
+	// Stale entries are refreshed in the background after this returns, so
+	// the fetch may record its time concurrently
+	var upstream atomic.Int64
+	fetch := func(ctx context.Context) (*youtube.TranscriptResponse, error) {
+		start := time.Now()
+		resp, err := s.fetcher.GetTranscript(ctx, req.VideoID, fetchOpts...)
+		upstream.Store(int64(time.Since(start)))
+		if err != nil {
+			s.metrics.recordUpstream(err)
+		}
+		if errors.Is(err, youtube.ErrTrackNotFound) {
+			s.logger.Warn("No transcript in requested language", "video_id", req.VideoID, "language", req.Language)
+			return nil, ErrNoTranscript
+		}
 		if err != nil {
-			s.client.Logger().Error("Failed to fetch raw transcript", "video_id", req.VideoID, "error", err)
-			return TranscriptResponse{}, fmt.Errorf("%w: %v", ErrFailedToGet, err)
+			s.logger.Error("Failed to fetch raw transcript", "video_id", req.VideoID, "error", err)
+			return nil, fmt.Errorf("%w: %w", ErrFailedToGet, err)
 		}
 
 		// Validate YouTube response
-		if youtubeResp == nil || youtubeResp.Raw == nil || len(youtubeResp.Raw.Segments) == 0 {
-			s.client.Logger().Warn("No transcript available", "video_id", req.VideoID)
-			return TranscriptResponse{}, ErrNoTranscript
+		if resp == nil || resp.Raw == nil || len(resp.Raw.Segments) == 0 {
+			s.metrics.recordClass(youtube.ErrorClassNoCaptions)
+			s.logger.Warn("No transcript available", "video_id", req.VideoID)
+			return nil, ErrNoTranscript
 		}
+		s.metrics.recordClass("")
 
-		// Save the successful response
-		if err := s.repo.Save(ctx, req.VideoID, youtubeResp); err != nil {
-			s.client.Logger().Error("Failed to cache transcript", "video_id", req.VideoID, "error", err)
-			// Continue despite cache error
+		// Transcripts fetched as the OAuth account may be private
+		if req.Account {
+			return resp, nil
 		}
+		s.shadow.compare(ctx, s.logger, req.VideoID, fetchOpts, resp)
+		s.vault.write(s.logger, req.VideoID, resp)
+		s.index(req.VideoID, resp)
+		return resp, nil
+	}
+
+	var (
+		youtubeResp *youtube.TranscriptResponse
+		cacheStatus CacheStatus
+		err         error
+	)
+	if req.Account {
+		youtubeResp, err = fetch(ctx)
+		cacheStatus = CacheMiss
+	} else {
+		youtubeResp, cacheStatus, err = s.repo.GetOrFetch(ctx, req.cacheKey(), fetch)
+	}
+	if err != nil {
+		return TranscriptResponse{}, err
+	}
+
+	formatStart := time.Now()
+
+	// Strip noise before formatting
+	segments, removed := format.Clean(youtubeResp.Raw.Segments, req.Clean)
+	from := max(req.FromSeconds, startSeconds)
+	if from > 0 || req.ToSeconds > 0 {
+		segments = segmentsBetween(segments, from, req.ToSeconds)
+	}
+
+	omitted := 0
+	if req.TokenBudget > 0 {
+		model := req.Model
+		if model == "" {
+			model = format.DefaultTokenModel
+		}
+		tokenizer, _ := format.TokenizerFor(model)
+		reduced := format.Reduce(segments, req.Strategy, req.TokenBudget, tokenizer)
+		omitted = len(segments) - len(reduced)
+		segments = reduced
 	}
 
 	// Create response
 	resp := TranscriptResponse{
-		Title: youtubeResp.Title,
-		Raw:   youtubeResp.Raw,
+		VideoID:  req.VideoID,
+		Title:    youtubeResp.Title,
+		Language: youtubeResp.Language,
+		Raw:      &youtube.Transcript{Segments: segments},
+		Stats: TranscriptStats{
+			Segments:        len(segments),
+			RemovedTokens:   removed,
+			OmittedSegments: omitted,
+		},
+		Cache:        cacheStatus,
+		StartSeconds: startSeconds,
+	}
+	if guess, ok := format.DetectLanguage(youtubeResp.Raw.Segments); ok {
+		resp.DetectedLanguage = &guess
+		if resp.Language == "" {
+			resp.Language = guess.Language
+		}
+	}
+	if (from > 0 || req.ToSeconds > 0) && len(segments) > 0 {
+		last := segments[len(segments)-1]
+		resp.Range = &TimeRange{
+			From: segments[0].StartTime,
+			To:   last.StartTime + last.Duration,
+		}
 	}
 
 	// Format the transcript
-	formatted, err := s.client.FormatTranscript(ctx, youtubeResp.Raw, interval)
-	if err != nil {
-		s.client.Logger().Error("Failed to format transcript", "video_id", req.VideoID, "error", err)
-		return TranscriptResponse{}, fmt.Errorf("%w: %v", ErrFailedToFormat, err)
+	style := req.TimestampStyle
+	if style == "" {
+		style = s.timestampStyle(resp.Language)
+	}
+	resp.Chapters = youtubeResp.Chapters
+	if req.GroupBy == GroupByChapter && len(resp.Chapters) > 0 {
+		resp.Formatted = format.ChaptersStyled(segments, resp.Chapters, style)
+	} else {
+		resp.Formatted = format.IntervalStyled(segments, interval, style)
+	}
+
+	if cacheStatus == CacheMiss {
+		resp.Timing.Upstream = time.Duration(upstream.Load())
+	}
+	resp.Timing.Format = time.Since(formatStart)
+	if req.Debug {
+		resp.Debug = &DebugInfo{
+			Cache:      resp.Cache,
+			UpstreamMs: milliseconds(resp.Timing.Upstream),
+			FormatMs:   milliseconds(resp.Timing.Format),
+		}
 	}
-	resp.Formatted = formatted
 
 	return resp, nil
 }
 
+// milliseconds converts d to fractional milliseconds rounded to microseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Groupings of formatted lines
+const (
+	GroupByInterval = "interval"
+	// GroupByChapter falls back to intervals for videos without chapters
+	GroupByChapter = "chapter"
+)
+
+// MaxLanguages bounds the languages fetched by one GetTranscriptsMulti call
+const MaxLanguages = 5
+
+// GetTranscriptsMulti fetches the transcript of the video in each of langs
+// concurrently. Languages that fail are kept with their error for the router
+// to describe in Errors; an error is only returned when none succeeds.
+func (s *Service) GetTranscriptsMulti(ctx context.Context, req TranscriptRequest, langs []string) (MultiTranscriptResponse, error) {
+	results := make([]TranscriptResponse, len(langs))
+	errs := make([]error, len(langs))
+
+	var wg sync.WaitGroup
+	for i, lang := range langs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			langReq := req
+			langReq.Language = lang
+			results[i], errs[i] = s.GetTranscripts(ctx, langReq)
+		}()
+	}
+	wg.Wait()
+
+	resp := MultiTranscriptResponse{Transcripts: make(map[string]TranscriptResponse)}
+	var firstErr error
+	for i, lang := range langs {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			if resp.errs == nil {
+				resp.errs = make(map[string]error)
+			}
+			resp.errs[lang] = errs[i]
+			continue
+		}
+		resp.VideoID = results[i].VideoID
+		if resp.Title == "" {
+			resp.Title = results[i].Title
+		}
+		resp.Transcripts[lang] = results[i]
+	}
+	if len(resp.Transcripts) == 0 {
+		return MultiTranscriptResponse{}, firstErr
+	}
+	return resp, nil
+}
+
+// RawCaptions returns the caption track of videoID in lang exactly as served
+// upstream, in one of youtube.RawCaptionFormats.
+func (s *Service) RawCaptions(ctx context.Context, videoID, lang, format string) (*youtube.RawCaptions, error) {
+	fetcher, ok := s.fetcher.(RawCaptionFetcher)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	raw, err := fetcher.GetRawCaptions(ctx, videoID, lang, format)
+	s.metrics.recordUpstream(err)
+	if err != nil {
+		if errors.Is(err, youtube.ErrTrackNotFound) {
+			return nil, ErrNoTranscript
+		}
+		s.logger.Error("Failed to fetch raw captions", "video_id", videoID, "language", lang, "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrFailedToGet, err)
+	}
+	return raw, nil
+}
+
+// CaptionAvailability reports whether videoID has captions and in which
+// languages, without downloading or parsing a caption file.
+func (s *Service) CaptionAvailability(ctx context.Context, videoID string) (CaptionAvailability, error) {
+	lister, ok := s.fetcher.(CaptionLister)
+	if !ok {
+		return CaptionAvailability{}, ErrNotSupported
+	}
+
+	tracks, err := lister.ListCaptionTracks(ctx, videoID)
+	s.metrics.recordUpstream(err)
+	if err != nil {
+		s.logger.Error("Failed to list caption tracks", "video_id", videoID, "error", err)
+		return CaptionAvailability{}, fmt.Errorf("%w: %v", ErrFailedToGet, err)
+	}
+
+	availability := CaptionAvailability{
+		VideoID:   videoID,
+		Exists:    len(tracks) > 0,
+		Languages: []string{},
+		Tracks:    []CaptionInfo{},
+	}
+	for _, track := range tracks {
+		if !slices.Contains(availability.Languages, track.LanguageCode) {
+			availability.Languages = append(availability.Languages, track.LanguageCode)
+		}
+		availability.Tracks = append(availability.Tracks, CaptionInfo{
+			Language:  track.LanguageCode,
+			Name:      track.Name,
+			Automatic: track.Kind == "asr",
+		})
+	}
+	return availability, nil
+}
+
+// Status reports which artifacts are available for videoID without fetching
+// anything upstream.
+func (s *Service) Status(ctx context.Context, videoID string) (VideoStatus, error) {
+	status := VideoStatus{VideoID: videoID}
+
+	info, err := s.repo.Stat(ctx, videoID)
+	if errors.Is(err, ErrTranscriptNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return VideoStatus{}, err
+	}
+
+	status.Transcript = TranscriptStatus{
+		Cached:   true,
+		Language: info.Language,
+		Source:   info.Source,
+		Segments: info.Segments,
+		CachedAt: &info.CachedAt,
+	}
+	return status, nil
+}
+
+// Ingest stores an externally obtained transcript, such as an uploaded
+// subtitle file, so that it is served like a fetched one. When videoID is
+// empty an ID is derived from the content. The ID used is returned.
+func (s *Service) Ingest(ctx context.Context, videoID, title string, segments []youtube.TranscriptSegment) (string, error) {
+	if len(segments) == 0 {
+		return "", ErrNoTranscript
+	}
+	if videoID == "" {
+		videoID = contentID(segments)
+	}
+
+	transcript := &youtube.TranscriptResponse{
+		Title:  title,
+		Source: "upload",
+		Raw:    &youtube.Transcript{Segments: segments},
+	}
+	if err := s.repo.Save(ctx, videoID, transcript); err != nil {
+		return "", err
+	}
+	s.vault.write(s.logger, videoID, transcript)
+	s.index(videoID, transcript)
+
+	s.logger.Info("Ingested transcript", "video_id", videoID, "segments", len(segments))
+	return videoID, nil
+}
+
+// SetUploadToken lets uploads carrying token as a bearer token be stored
+// under a YouTube video ID, replacing what every user gets for that video.
+// Other uploads are only stored under their content ID. Transcript requests
+// with the token may use the OAuth account. An empty token allows neither.
+func (s *Service) SetUploadToken(token string) {
+	s.uploadToken = token
+}
+
+// isAdmin reports whether req carries the upload token, letting it store
+// uploads under a YouTube video ID and fetch as the OAuth account.
+func (s *Service) isAdmin(req *http.Request) bool {
+	if s.uploadToken == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(s.uploadToken)) == 1
+}
+
+// contentID derives a stable ID for ingested transcripts from their segments
+func contentID(segments []youtube.TranscriptSegment) string {
+	h := sha256.New()
+	for _, segment := range segments {
+		fmt.Fprintf(h, "%.3f\x00%s\x00", segment.StartTime, segment.Text)
+	}
+	return "upload-" + hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+var (
+	// startTimePattern matches t= values such as "90", "90s" or "1h2m3s"
+	startTimePattern = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+	// clockTimePattern matches "mm:ss" and "hh:mm:ss"
+	clockTimePattern = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2})$`)
+)
+
+// StartTimeFromURL returns the start time in seconds given by the t or start
+// query parameter, or a #t= fragment, of a YouTube URL. It returns 0 when there
+// is none.
+func StartTimeFromURL(urlStr string) float64 {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return 0
+	}
+
+	query := parsedURL.Query()
+	value := query.Get("t")
+	if value == "" {
+		value = query.Get("start")
+	}
+	if value == "" {
+		if fragment, ok := strings.CutPrefix(parsedURL.Fragment, "t="); ok {
+			value = fragment
+		}
+	}
+
+	seconds, ok := parseTimeOffset(value)
+	if !ok {
+		return 0
+	}
+	return seconds
+}
+
+// parseTimeOffset parses a position in a video given as seconds ("90",
+// "90.5"), YouTube style ("1m30s") or clock style ("1:30", "1:02:03").
+// Negative, infinite and NaN offsets are rejected.
+func parseTimeOffset(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+			return 0, false
+		}
+		return seconds, true
+	}
+
+	m := startTimePattern.FindStringSubmatch(value)
+	if m == nil {
+		m = clockTimePattern.FindStringSubmatch(value)
+	}
+	if m == nil {
+		return 0, false
+	}
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if m[i+1] != "" {
+			n, _ := strconv.Atoi(m[i+1])
+			seconds += n * unit
+		}
+	}
+	return float64(seconds), true
+}
+
+// segmentsBetween keeps the segments overlapping [from, to). A zero to means
+// until the end.
+func segmentsBetween(segments []youtube.TranscriptSegment, from, to float64) []youtube.TranscriptSegment {
+	start := len(segments)
+	for i, segment := range segments {
+		if segment.StartTime+segment.Duration > from {
+			start = i
+			break
+		}
+	}
+	end := len(segments)
+	if to > 0 {
+		for i := start; i < len(segments); i++ {
+			if segments[i].StartTime >= to {
+				end = i
+				break
+			}
+		}
+	}
+	return segments[start:end]
+}
+
 // ExtractVideoId attempts to extract a YouTube video ID from a string.
 // It can handle both direct 11-character IDs and various URL formats.
 // Returns empty string if no valid video ID is found.
@@ -106,6 +569,14 @@ func (s *Service) ExtractVideoId(str string) string {
 		return str
 	}
 
+	// Attribution links carry the watch URL URL-encoded in their u parameter,
+	// e.g. /attribution_link?a=x&u=%2Fwatch%3Fv%3D...
+	if parsedURL, err := url.Parse(str); err == nil && strings.TrimSuffix(parsedURL.Path, "/") == "/attribution_link" {
+		if target := parsedURL.Query().Get("u"); target != "" {
+			return s.ExtractVideoId(target)
+		}
+	}
+
 	// Regular expression to match YouTube video ID in various URL formats
 	pattern := `(?:\/|%3D|v=|vi=)([a-zA-Z0-9_-]{11})(?:[%#?&\/]|$)`
 	regex := regexp.MustCompile(pattern)
@@ -119,7 +590,8 @@ func (s *Service) ExtractVideoId(str string) string {
 }
 
 // IsValidUrl checks if the provided URL has a valid YouTube domain.
-// It handles domains: youtu.be, youtube.com, m.youtube.com, with or without www.
+// It handles domains: youtu.be, youtube.com, m.youtube.com, music.youtube.com,
+// gaming.youtube.com, with or without www.
 // Returns true if the domain is a valid YouTube domain, false otherwise.
 func (s *Service) IsValidUrl(urlStr string) bool {
 	// Parse the URL
@@ -139,6 +611,8 @@ func (s *Service) IsValidUrl(urlStr string) bool {
 		"youtube.com",
 		"youtu.be",
 		"m.youtube.com",
+		"music.youtube.com",
+		"gaming.youtube.com",
 	}
 
 	// Check if the host matches any valid domain
@@ -0,0 +1,149 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+func TestExtractVideoId(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bare id", "dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"watch", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"watch with params", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s", "dQw4w9WgXcQ"},
+		{"short link", "https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"short link with time", "https://youtu.be/dQw4w9WgXcQ?t=42", "dQw4w9WgXcQ"},
+		{"mobile", "https://m.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"music", "https://music.youtube.com/watch?v=dQw4w9WgXcQ&list=RDAMVM", "dQw4w9WgXcQ"},
+		{"gaming", "https://gaming.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"embed", "https://www.youtube.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"shorts", "https://www.youtube.com/shorts/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"legacy v", "https://www.youtube.com/v/dQw4w9WgXcQ?version=3", "dQw4w9WgXcQ"},
+		{"attribution link", "https://www.youtube.com/attribution_link?a=abc&u=%2Fwatch%3Fv%3DdQw4w9WgXcQ%26feature%3Dshare", "dQw4w9WgXcQ"},
+		{"attribution link trailing slash", "https://www.youtube.com/attribution_link/?u=%2Fwatch%3Fv%3DdQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"too short", "https://www.youtube.com/watch?v=dQw4w9", ""},
+		{"no id", "https://www.youtube.com/feed/trending", ""},
+		{"empty", "", ""},
+	}
+	s := &Service{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.ExtractVideoId(tt.in); got != tt.want {
+				t.Errorf("ExtractVideoId(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidUrl(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", true},
+		{"https://youtube.com/watch?v=dQw4w9WgXcQ", true},
+		{"https://WWW.YouTube.com/watch?v=dQw4w9WgXcQ", true},
+		{"https://youtu.be/dQw4w9WgXcQ", true},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", true},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", true},
+		{"https://gaming.youtube.com/watch?v=dQw4w9WgXcQ", true},
+		{"https://www.youtube.com/attribution_link?u=%2Fwatch%3Fv%3DdQw4w9WgXcQ", true},
+		{"https://youtube.com.evil.example/watch?v=dQw4w9WgXcQ", false},
+		{"https://notyoutube.com/watch?v=dQw4w9WgXcQ", false},
+		{"https://vimeo.com/123456", false},
+		{"dQw4w9WgXcQ", false},
+		{"://bad", false},
+	}
+	s := &Service{}
+	for _, tt := range tests {
+		if got := s.IsValidUrl(tt.in); got != tt.want {
+			t.Errorf("IsValidUrl(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStartTimeFromURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want float64
+	}{
+		{"seconds", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=90", 90},
+		{"fractional", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=90.5", 90.5},
+		{"youtube style", "https://youtu.be/dQw4w9WgXcQ?t=1m30s", 90},
+		{"clock style", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1:02:03", 3723},
+		{"start", "https://www.youtube.com/embed/dQw4w9WgXcQ?start=42", 42},
+		{"fragment", "https://www.youtube.com/watch?v=dQw4w9WgXcQ#t=42", 42},
+		{"none", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", 0},
+		{"garbage", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=soon", 0},
+		{"infinite", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=inf", 0},
+		{"nan", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=nan", 0},
+		{"negative", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=-5", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StartTimeFromURL(tt.in); got != tt.want {
+				t.Errorf("StartTimeFromURL(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// langFetcher serves testTranscript in English and fails every other language
+// like a player request to a failing endpoint
+type langFetcher struct{}
+
+func (langFetcher) GetTranscript(ctx context.Context, videoID string, opts ...youtube.RequestOption) (*youtube.TranscriptResponse, error) {
+	var o youtube.RequestOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Language == "en" {
+		return testTranscript(), nil
+	}
+	return nil, &url.Error{
+		Op:  "Post",
+		URL: "https://www.youtube.com/youtubei/v1/player?key=secret-api-key",
+		Err: io.ErrUnexpectedEOF,
+	}
+}
+
+func TestMultiLanguageErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mux := NewRouter(NewService(langFetcher{}, NewMemoryRepository(logger), logger), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/transcripts?videoId=dQw4w9WgXcQ&langs=en,de", nil)
+	req.Header.Set("Accept-Language", "tr")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret-api-key") {
+		t.Fatalf("response leaks the upstream error: %s", w.Body)
+	}
+
+	var resp MultiTranscriptResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Transcripts["en"]; !ok {
+		t.Errorf("transcripts = %v, want en", resp.Transcripts)
+	}
+	if want := i18n.T("tr", i18n.InternalError); resp.Errors["de"] != want {
+		t.Errorf("errors[de] = %q, want %q", resp.Errors["de"], want)
+	}
+}
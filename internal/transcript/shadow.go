@@ -0,0 +1,100 @@
+package transcript
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// shadowTimeout bounds a shadow fetch, which runs detached from the request
+const shadowTimeout = 30 * time.Second
+
+// shadow re-fetches a sample of transcripts with an experimental client
+// configuration and logs how its output differs from the primary client,
+// without affecting responses.
+type shadow struct {
+	fetcher    TranscriptFetcher
+	sampleRate float64
+}
+
+// SetShadow enables shadow mode: a sampleRate fraction of upstream fetches is
+// repeated in the background with fetcher and compared to the primary result.
+func (s *Service) SetShadow(fetcher TranscriptFetcher, sampleRate float64) {
+	if fetcher == nil || sampleRate <= 0 {
+		s.shadow = nil
+		return
+	}
+	s.shadow = &shadow{fetcher: fetcher, sampleRate: min(sampleRate, 1)}
+}
+
+// compare runs the shadow fetch for videoID in the background
+func (sh *shadow) compare(ctx context.Context, logger *slog.Logger, videoID string, opts []youtube.RequestOption, primary *youtube.TranscriptResponse) {
+	if sh == nil || rand.Float64() >= sh.sampleRate {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shadowTimeout)
+		defer cancel()
+
+		logger := logger.With("video_id", videoID, "shadow", true)
+		start := time.Now()
+		candidate, err := sh.fetcher.GetTranscript(ctx, videoID, opts...)
+		if err != nil {
+			logger.Warn("Shadow fetch failed", "error", err)
+			return
+		}
+
+		primarySegments := segmentsOf(primary)
+		candidateSegments := segmentsOf(candidate)
+		logger.Info("Shadow fetch compared",
+			"duration", time.Since(start),
+			"primary_source", primary.Source,
+			"shadow_source", candidate.Source,
+			"primary_segments", len(primarySegments),
+			"shadow_segments", len(candidateSegments),
+			"text_similarity", textSimilarity(primarySegments, candidateSegments),
+			"title_match", primary.Title == candidate.Title,
+		)
+	}()
+}
+
+func segmentsOf(resp *youtube.TranscriptResponse) []youtube.TranscriptSegment {
+	if resp == nil || resp.Raw == nil {
+		return nil
+	}
+	return resp.Raw.Segments
+}
+
+// textSimilarity returns the Dice coefficient of the word multisets of a and
+// b: 1 for identical text, 0 for no words in common.
+func textSimilarity(a, b []youtube.TranscriptSegment) float64 {
+	counts := make(map[string]int)
+	total := 0
+	for _, segment := range a {
+		for _, word := range strings.Fields(strings.ToLower(segment.Text)) {
+			counts[word]++
+			total++
+		}
+	}
+
+	common := 0
+	for _, segment := range b {
+		for _, word := range strings.Fields(strings.ToLower(segment.Text)) {
+			if counts[word] > 0 {
+				counts[word]--
+				common++
+			}
+			total++
+		}
+	}
+
+	if total == 0 {
+		return 1
+	}
+	return 2 * float64(common) / float64(total)
+}
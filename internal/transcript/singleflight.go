@@ -0,0 +1,64 @@
+package transcript
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// flightCall is an in-flight or completed fetch for a single key. done is
+// closed once val and err are set.
+type flightCall struct {
+	done chan struct{}
+	val  *youtube.TranscriptResponse
+	err  error
+}
+
+// flightGroup deduplicates concurrent fetches for the same key so that only
+// one upstream request is made while the others wait for its result.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do runs fn for key unless a call for key is in flight, and waits for the
+// shared result or for ctx to be done. fn runs on its own goroutine, so a
+// caller going away neither cancels it nor fails the other callers.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (*youtube.TranscriptResponse, error)) (*youtube.TranscriptResponse, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run calls fn and releases the waiters of c, turning a panic in fn into
+// an error so that no caller waits forever.
+func (g *flightGroup) run(key string, c *flightCall, fn func() (*youtube.TranscriptResponse, error)) {
+	defer func() {
+		if v := recover(); v != nil {
+			c.val, c.err = nil, fmt.Errorf("fetch panicked: %v", v)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+}
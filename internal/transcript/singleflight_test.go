@@ -0,0 +1,53 @@
+package transcript
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+func TestFlightGroupWaiterCancel(t *testing.T) {
+	var g flightGroup
+	started, release := make(chan struct{}), make(chan struct{})
+	go g.do(context.Background(), "key", func() (*youtube.TranscriptResponse, error) {
+		close(started)
+		<-release
+		return testTranscript(), nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := g.do(ctx, "key", func() (*youtube.TranscriptResponse, error) {
+		t.Error("second call ran while the first was in flight")
+		return nil, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter error = %v, want its context's deadline", err)
+	}
+	close(release)
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var g flightGroup
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := g.do(ctx, "key", func() (*youtube.TranscriptResponse, error) {
+		panic("boom")
+	})
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want the panic as an error", err)
+	}
+
+	// The key is released for later callers
+	resp, err := g.do(ctx, "key", func() (*youtube.TranscriptResponse, error) {
+		return testTranscript(), nil
+	})
+	if err != nil || resp == nil {
+		t.Errorf("call after panic = %v, %v, want the transcript", resp, err)
+	}
+}
@@ -0,0 +1,46 @@
+package transcript
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+)
+
+// SetTimestampStyles lays out the timestamps of formatted transcripts in def,
+// or in the style of perLanguage for transcripts in that language. Keys are
+// language codes such as "de" or "pt-BR"; "pt" also covers "pt-BR". Requests
+// may override both with timestampStyle.
+func (s *Service) SetTimestampStyles(def string, perLanguage map[string]string) error {
+	styles := make(map[string]format.TimestampStyle, len(perLanguage)+1)
+	for lang, style := range perLanguage {
+		if !languagePattern.MatchString(lang) {
+			return fmt.Errorf("invalid timestamp style language %q", lang)
+		}
+		parsed, err := format.ParseTimestampStyle(style)
+		if err != nil {
+			return fmt.Errorf("timestamp style for %s: %w", lang, err)
+		}
+		styles[strings.ToLower(lang)] = parsed
+	}
+	parsed, err := format.ParseTimestampStyle(def)
+	if err != nil {
+		return err
+	}
+	styles[""] = parsed
+	s.timestampStyles = styles
+	return nil
+}
+
+// timestampStyle returns the style for a transcript in lang, preferring an
+// exact language match over its base language and the default.
+func (s *Service) timestampStyle(lang string) format.TimestampStyle {
+	lang = strings.ToLower(lang)
+	base, _, _ := strings.Cut(lang, "-")
+	for _, key := range []string{lang, base, ""} {
+		if style, ok := s.timestampStyles[key]; ok {
+			return style
+		}
+	}
+	return format.DefaultTimestampStyle
+}
@@ -1,20 +1,483 @@
 package transcript
 
-import "github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// videoIDPattern restricts caller supplied IDs to URL and log safe characters
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var (
+	regionPattern   = regexp.MustCompile(`^[A-Za-z]{2}$`)
+	languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(?:-[A-Za-z0-9]{2,8})*$`)
+)
 
 type TranscriptRequest struct {
 	VideoURL        string
 	VideoID         string
 	IntervalSeconds float64
+	Clean           format.CleanOptions
+	// Region and UILanguage are the InnerTube gl and hl overrides
+	Region     string
+	UILanguage string
+	// Language selects the caption track language
+	Language string
+	// Languages requests the transcript in several languages at once
+	Languages []string
+	// OffsetSeconds and TimeScale retime subtitle exports
+	OffsetSeconds float64
+	TimeScale     float64
+	// Cues merges short segments in subtitle exports when set
+	Cues *format.CueOptions
+	// Metadata adds video fields to every line of JSONL exports
+	Metadata bool
+	// Debug includes cache and timing details in JSON responses
+	Debug bool
+	// TokenBudget, when positive, reduces the transcript with Strategy to
+	// fit this many tokens of Model's tokenizer
+	TokenBudget int
+	Strategy    string
+	Model       string
+	// RespectStartTime begins the transcript at the t= start time of VideoURL
+	RespectStartTime bool
+	// FromSeconds and ToSeconds limit the transcript to part of the video, a
+	// zero ToSeconds meaning until the end
+	FromSeconds float64
+	ToSeconds   float64
+	// TimestampStyle overrides the configured style of formatted timestamps
+	TimestampStyle format.TimestampStyle
+	// GroupBy is GroupByChapter to format one line per chapter, otherwise
+	// lines start every IntervalSeconds
+	GroupBy string
+	// Confidence fetches automatic tracks with per segment speech
+	// recognition confidence
+	Confidence bool
+	// Account lets the fetch fall back to the deployment's OAuth account.
+	// It is only set for requests with the admin token, and such requests
+	// bypass the cache.
+	Account bool
+}
+
+// cacheKey separates cached transcripts fetched with different InnerTube
+// overrides, whose titles and track availability may differ.
+// Transcripts in a requested caption language or with confidence are cached
+// separately too.
+func (r TranscriptRequest) cacheKey() string {
+	key := r.VideoID
+	if r.Region != "" || r.UILanguage != "" {
+		key += "@" + r.UILanguage + "-" + r.Region
+	}
+	if r.Language != "" || r.Confidence {
+		key += "#" + r.Language
+	}
+	if r.Confidence {
+		key += "+confidence"
+	}
+	return key
 }
 
 type TranscriptResponse struct {
+	VideoID   string              `json:"videoId"`
 	Title     string              `json:"title"`
+	Language  string              `json:"language,omitempty"`
 	Raw       *youtube.Transcript `json:"raw"`
 	Formatted []string            `json:"formatted"`
+	Stats     TranscriptStats     `json:"stats"`
+	// DetectedLanguage is guessed from the text, as tracks may be mislabeled
+	// or, for uploads, unlabeled
+	DetectedLanguage *format.LanguageGuess `json:"detectedLanguage,omitempty"`
+	// StartSeconds is the video URL's start time the transcript begins at
+	// when respectStartTime is set
+	StartSeconds float64 `json:"startSeconds,omitempty"`
+	// Chapters are the creator's chapters of the video, if any
+	Chapters []youtube.Chapter `json:"chapters,omitempty"`
+	// Range is the span covered when the transcript was limited with from,
+	// to or respectStartTime
+	Range *TimeRange `json:"range,omitempty"`
+	// Cache reports how the transcript was obtained, sent as X-Cache
+	Cache CacheStatus `json:"-"`
+	// Timing is sent as response headers and, on request, as Debug
+	Timing Timing     `json:"-"`
+	Debug  *DebugInfo `json:"debug,omitempty"`
+}
+
+// MultiTranscriptResponse holds the transcripts of a video per requested
+// language
+type MultiTranscriptResponse struct {
+	VideoID     string                        `json:"videoId"`
+	Title       string                        `json:"title"`
+	Transcripts map[string]TranscriptResponse `json:"transcripts"`
+	// Errors describes languages that could not be fetched, in the client's
+	// language
+	Errors map[string]string `json:"errors,omitempty"`
+
+	errs map[string]error
+}
+
+// CaptionAvailability is served at /api/v1/videos/{id}/captions/exists
+type CaptionAvailability struct {
+	VideoID string `json:"videoId"`
+	Exists  bool   `json:"exists"`
+	// Languages lists each available language code once
+	Languages []string      `json:"languages"`
+	Tracks    []CaptionInfo `json:"tracks"`
+}
+
+// CaptionInfo describes an available caption track
+type CaptionInfo struct {
+	Language string `json:"language"`
+	Name     string `json:"name,omitempty"`
+	// Automatic is set for speech recognition tracks
+	Automatic bool `json:"automatic"`
+}
+
+// TimeRange is a span of the video in seconds
+type TimeRange struct {
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+}
+
+// Timing breaks down where the time of a request was spent
+type Timing struct {
+	// Upstream is spent fetching from YouTube, zero for cache hits
+	Upstream time.Duration
+	// Format is spent cleaning and formatting the transcript
+	Format time.Duration
+}
+
+// DebugInfo is included in transcript responses when debug=true
+type DebugInfo struct {
+	Cache      CacheStatus `json:"cache"`
+	UpstreamMs float64     `json:"upstreamMs"`
+	FormatMs   float64     `json:"formatMs"`
+}
+
+// TokenCount is the estimated token count of a cleaned transcript
+type TokenCount struct {
+	VideoID   string `json:"videoId"`
+	Model     string `json:"model"`
+	Tokenizer string `json:"tokenizer"`
+	// Estimated is always true: counts are approximated, not tokenized
+	Estimated bool `json:"estimated"`
+	format.TokenEstimate
+}
+
+// VideoHighlights lists the moments suggested for a highlight reel
+type VideoHighlights struct {
+	VideoID    string             `json:"videoId"`
+	Title      string             `json:"title"`
+	Language   string             `json:"language,omitempty"`
+	Highlights []format.Highlight `json:"highlights"`
+}
+
+// ExtSummary is the short form of a transcript served to browser extensions
+// at /api/v1/ext/summary
+type ExtSummary struct {
+	VideoID  string `json:"videoId"`
+	Title    string `json:"title"`
+	Language string `json:"language,omitempty"`
+	// Duration is the end of the last segment in seconds
+	Duration float64 `json:"duration"`
+	// Lines holds one "(mm:ss) text" line per interval
+	Lines []string `json:"lines"`
+}
+
+// compactExtSummary is ExtSummary with single letter keys, sent for
+// compact=true
+type compactExtSummary struct {
+	VideoID  string   `json:"v"`
+	Title    string   `json:"t"`
+	Language string   `json:"l,omitempty"`
+	Duration float64  `json:"d"`
+	Lines    []string `json:"x"`
+}
+
+// APIInfo is served at "/" when the web UI is disabled
+type APIInfo struct {
+	Name      string   `json:"name"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// VideoStatus lists the artifacts that exist for a video
+type VideoStatus struct {
+	VideoID    string           `json:"videoId"`
+	Transcript TranscriptStatus `json:"transcript"`
+}
+
+// TranscriptStatus describes the cached transcript of a video
+type TranscriptStatus struct {
+	Cached   bool       `json:"cached"`
+	Language string     `json:"language,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Segments int        `json:"segments,omitempty"`
+	CachedAt *time.Time `json:"cachedAt,omitempty"`
+}
+
+// TranscriptStats describes the processing applied to a transcript
+type TranscriptStats struct {
+	Segments      int `json:"segments"`
+	RemovedTokens int `json:"removedTokens"`
+	// OmittedSegments were dropped to fit the token budget
+	OmittedSegments int `json:"omittedSegments,omitempty"`
 }
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error   string       `json:"error"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// TranscriptQuery holds the raw query parameters of GET /api/v1/transcripts
+type TranscriptQuery struct {
+	VideoURL string
+	VideoID  string
+	Interval string
+	Format   string
+	Clean    string
+	Region   string
+	UILang   string
+	Offset   string
+	Scale    string
+	// Cue merging for subtitle exports
+	Merge          string
+	MinCueDuration string
+	MaxCueChars    string
+	MaxCPS         string
+	LineLength     string
+	Meta           string
+	Debug          string
+	// Reduction to a token budget
+	Budget   string
+	Strategy string
+	Model    string
+
+	RespectStartTime string
+	From             string
+	To               string
+	Lang             string
+	Langs            string
+	TimestampStyle   string
+	GroupBy          string
+	Confidence       string
+}
+
+func newTranscriptQuery(values url.Values) TranscriptQuery {
+	return TranscriptQuery{
+		VideoURL: values.Get("videoUrl"),
+		VideoID:  values.Get("videoId"),
+		Interval: values.Get("interval"),
+		Format:   values.Get("format"),
+		Clean:    values.Get("clean"),
+		Region:   values.Get("region"),
+		UILang:   values.Get("uiLang"),
+		Offset:   values.Get("offset"),
+		Scale:    values.Get("scale"),
+
+		Merge:          values.Get("merge"),
+		MinCueDuration: values.Get("minCueDuration"),
+		MaxCueChars:    values.Get("maxCueChars"),
+		MaxCPS:         values.Get("maxCps"),
+		LineLength:     values.Get("lineLength"),
+		Meta:           values.Get("meta"),
+		Debug:          values.Get("debug"),
+		Budget:         values.Get("budget"),
+		Strategy:       values.Get("strategy"),
+		Model:          values.Get("model"),
+
+		RespectStartTime: values.Get("respectStartTime"),
+		From:             values.Get("from"),
+		To:               values.Get("to"),
+		Lang:             values.Get("lang"),
+		Langs:            values.Get("langs"),
+		TimestampStyle:   values.Get("timestampStyle"),
+		GroupBy:          values.Get("groupBy"),
+		Confidence:       values.Get("confidence"),
+	}
+}
+
+// Bind validates the query and converts it into a service request. All
+// invalid fields are reported together in a *ValidationError.
+func (q TranscriptQuery) Bind() (TranscriptRequest, error) {
+	var v validator
+
+	v.check(q.VideoURL != "" || q.VideoID != "", "videoUrl", i18n.VideoSourceRequired)
+	v.check(q.VideoID == "" || videoIDPattern.MatchString(q.VideoID), "videoId", i18n.InvalidVideoID)
+
+	var interval float64
+	if q.Interval != "" {
+		var err error
+		interval, err = strconv.ParseFloat(q.Interval, 64)
+		v.check(err == nil, "interval", i18n.NotANumber, q.Interval)
+		v.check(err != nil || (interval >= MinIntervalSeconds && interval <= MaxIntervalSeconds),
+			"interval", i18n.SecondsOutOfRange, MinIntervalSeconds, MaxIntervalSeconds)
+	}
+
+	_, known := format.Lookup(q.Format)
+	v.check(q.Format == "" || q.Format == "json" || known, "format", i18n.UnsupportedFormat, q.Format)
+
+	v.check(q.Region == "" || regionPattern.MatchString(q.Region), "region", i18n.InvalidRegion)
+	v.check(q.UILang == "" || languagePattern.MatchString(q.UILang), "uiLang", i18n.InvalidLanguage)
+
+	subtitles := q.Format == "srt" || q.Format == "vtt"
+	var offset float64
+	if q.Offset != "" {
+		var err error
+		offset, err = strconv.ParseFloat(q.Offset, 64)
+		v.check(err == nil, "offset", i18n.NotANumber, q.Offset)
+		v.check(err != nil || (offset >= -MaxOffsetSeconds && offset <= MaxOffsetSeconds),
+			"offset", i18n.SecondsOutOfRange, -MaxOffsetSeconds, MaxOffsetSeconds)
+		v.check(subtitles, "offset", i18n.SubtitleExportOnly)
+	}
+	scale := 1.0
+	if q.Scale != "" {
+		var err error
+		scale, err = strconv.ParseFloat(q.Scale, 64)
+		v.check(err == nil, "scale", i18n.NotANumber, q.Scale)
+		v.check(err != nil || (scale >= MinTimeScale && scale <= MaxTimeScale),
+			"scale", i18n.OutOfRange, MinTimeScale, MaxTimeScale)
+		v.check(subtitles, "scale", i18n.SubtitleExportOnly)
+	}
+
+	var cues *format.CueOptions
+	v.check(q.Merge == "" || q.Merge == "true" || q.Merge == "false", "merge", i18n.NotBoolean)
+	if q.Merge == "true" {
+		v.check(subtitles, "merge", i18n.SubtitleExportOnly)
+		cues = &format.CueOptions{
+			MinDuration: v.float("minCueDuration", q.MinCueDuration, 0.1, 10),
+			MaxChars:    int(v.float("maxCueChars", q.MaxCueChars, 10, 500)),
+			MaxCPS:      v.float("maxCps", q.MaxCPS, 5, 100),
+			LineLength:  int(v.float("lineLength", q.LineLength, 10, 200)),
+		}
+	}
+
+	v.check(q.Meta == "" || q.Meta == "true" || q.Meta == "false", "meta", i18n.NotBoolean)
+	v.check(q.Meta != "true" || q.Format == "jsonl", "meta", i18n.JSONLExportOnly)
+
+	v.check(q.Debug == "" || q.Debug == "true" || q.Debug == "false", "debug", i18n.NotBoolean)
+	v.check(q.Confidence == "" || q.Confidence == "true" || q.Confidence == "false", "confidence", i18n.NotBoolean)
+
+	budget := int(v.float("budget", q.Budget, MinTokenBudget, MaxTokenBudget))
+	v.check(q.Strategy == "" || slices.Contains(format.ReduceStrategies, q.Strategy),
+		"strategy", i18n.OneOf, strings.Join(format.ReduceStrategies, ", "))
+	v.check(q.Strategy == "" || q.Budget != "", "strategy", i18n.Requires, "budget")
+	_, knownModel := format.TokenizerFor(q.Model)
+	v.check(q.Model == "" || knownModel, "model", i18n.UnsupportedModel, q.Model, strings.Join(format.TokenModels(), ", "))
+
+	v.check(q.RespectStartTime == "" || q.RespectStartTime == "true" || q.RespectStartTime == "false",
+		"respectStartTime", i18n.NotBoolean)
+	v.check(q.RespectStartTime != "true" || q.VideoURL != "", "respectStartTime", i18n.Requires, "videoUrl")
+
+	from, fromOK := parseTimeOffset(q.From)
+	to, toOK := parseTimeOffset(q.To)
+	v.check(q.From == "" || fromOK, "from", i18n.InvalidTimeOffset, q.From)
+	v.check(q.To == "" || toOK, "to", i18n.InvalidTimeOffset, q.To)
+	v.check(!fromOK || !toOK || to > from, "to", i18n.MustBeAfter, "from")
+
+	v.check(q.Lang == "" || languagePattern.MatchString(q.Lang), "lang", i18n.InvalidLanguage)
+	var langs []string
+	if q.Langs != "" {
+		for _, lang := range strings.Split(q.Langs, ",") {
+			lang = strings.TrimSpace(lang)
+			v.check(languagePattern.MatchString(lang), "langs", i18n.NotALanguage, lang)
+			if !slices.Contains(langs, lang) {
+				langs = append(langs, lang)
+			}
+		}
+		v.check(len(langs) <= MaxLanguages, "langs", i18n.TooManyLanguages, MaxLanguages)
+		v.check(q.Lang == "", "langs", i18n.CannotCombine, "lang")
+		if q.Format != "" && q.Format != "json" {
+			v.check(slices.Contains(format.BilingualFormats, q.Format),
+				"langs", i18n.InterleaveFormats, strings.Join(format.BilingualFormats, ", "))
+			v.check(len(langs) == 2, "langs", i18n.InterleaveLanguages)
+			v.check(q.Merge != "true", "merge", i18n.NotWithInterleave)
+		}
+	}
+
+	var style format.TimestampStyle
+	if q.TimestampStyle != "" {
+		var err error
+		style, err = format.ParseTimestampStyle(q.TimestampStyle)
+		v.check(err == nil, "timestampStyle", i18n.InvalidTimestampStyle, err)
+	}
+
+	v.check(q.GroupBy == "" || q.GroupBy == GroupByInterval || q.GroupBy == GroupByChapter,
+		"groupBy", i18n.Either, GroupByInterval, GroupByChapter)
+	v.check(q.GroupBy != GroupByChapter || q.Interval == "", "interval", i18n.CannotCombine, "groupBy=chapter")
+
+	var clean format.CleanOptions
+	for _, option := range strings.Split(q.Clean, ",") {
+		switch strings.TrimSpace(option) {
+		case "", "false":
+		case "true", "all":
+			clean = format.CleanOptions{SoundTags: true, Fillers: true}
+		case "tags":
+			clean.SoundTags = true
+		case "fillers":
+			clean.Fillers = true
+		default:
+			v.check(false, "clean", i18n.UnknownCleanOption, option)
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return TranscriptRequest{}, err
+	}
+	return TranscriptRequest{
+		VideoURL:        q.VideoURL,
+		VideoID:         q.VideoID,
+		IntervalSeconds: interval,
+		Clean:           clean,
+		Region:          strings.ToUpper(q.Region),
+		UILanguage:      q.UILang,
+		OffsetSeconds:   offset,
+		TimeScale:       scale,
+		Cues:            cues,
+		Metadata:        q.Meta == "true",
+		Debug:           q.Debug == "true",
+		TokenBudget:     budget,
+		Strategy:        q.Strategy,
+		Model:           q.Model,
+
+		RespectStartTime: q.RespectStartTime == "true",
+		FromSeconds:      from,
+		ToSeconds:        to,
+		Language:         q.Lang,
+		Languages:        langs,
+		TimestampStyle:   style,
+		GroupBy:          q.GroupBy,
+		Confidence:       q.Confidence == "true",
+	}, nil
+}
+
+// UploadForm holds the fields of POST /api/v1/transcripts/upload
+type UploadForm struct {
+	FileName string
+	HasFile  bool
+	VideoID  string
+	Title    string
+	Format   string
+	// Language of the subtitle file, used with a yt-dlp info.json
+	Language string
+}
+
+// Validate reports every invalid field of the upload in a *ValidationError
+func (f UploadForm) Validate() error {
+	var v validator
+
+	v.check(f.HasFile, "file", i18n.FieldRequired)
+	v.check(f.VideoID == "" || videoIDPattern.MatchString(f.VideoID), "videoId", i18n.InvalidVideoID)
+	v.check(len(f.Title) <= 300, "title", i18n.TooLong, 300)
+	v.check(f.Format == "" || format.CanParse(f.Format), "format", i18n.UnsupportedSubtitleFormat, f.Format)
+	v.check(f.Language == "" || languagePattern.MatchString(f.Language), "language", i18n.InvalidLanguage)
+
+	return v.err()
 }
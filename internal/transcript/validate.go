@@ -0,0 +1,113 @@
+package transcript
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+)
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	// key and args render Message in the client's language
+	key  i18n.Key
+	args []any
+}
+
+// NewFieldError returns the error for field with the message key, formatted
+// with args. Message holds the English text.
+func NewFieldError(field string, key i18n.Key, args ...any) FieldError {
+	return FieldError{
+		Field:   field,
+		Message: i18n.Tf(i18n.DefaultLanguage, key, args...),
+		key:     key,
+		args:    args,
+	}
+}
+
+// ValidationError lists every invalid field of a request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f.Field, f.Message))
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// localized returns the field errors with their messages in lang
+func (e *ValidationError) localized(lang string) []FieldError {
+	fields := make([]FieldError, len(e.Fields))
+	for i, f := range e.Fields {
+		if f.key != "" {
+			f.Message = i18n.Tf(lang, f.key, f.args...)
+		}
+		fields[i] = f
+	}
+	return fields
+}
+
+// validator collects field errors so that all of them are reported at once
+// instead of failing on the first one.
+type validator struct {
+	fields []FieldError
+}
+
+// check records the message key for field unless ok holds.
+func (v *validator) check(ok bool, field string, key i18n.Key, args ...any) {
+	if !ok {
+		v.fields = append(v.fields, NewFieldError(field, key, args...))
+	}
+}
+
+// float parses an optional numeric field and checks it lies within
+// [lo, hi]. Empty or invalid values yield 0.
+func (v *validator) float(field, value string, lo, hi float64) float64 {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		v.check(false, field, i18n.NotANumber, value)
+		return 0
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) || n < lo || n > hi {
+		v.check(false, field, i18n.OutOfRange, lo, hi)
+		return 0
+	}
+	return n
+}
+
+// int parses an optional whole number field and checks it lies within
+// [lo, hi]. Empty or invalid values yield 0.
+func (v *validator) int(field, value string, lo, hi int) int {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		v.check(false, field, i18n.NotAWholeNumber, value)
+		return 0
+	}
+	if n < lo || n > hi {
+		v.check(false, field, i18n.OutOfRange, lo, hi)
+		return 0
+	}
+	return n
+}
+
+// err returns a *ValidationError when any check failed, nil otherwise.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
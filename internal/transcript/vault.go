@@ -0,0 +1,133 @@
+package transcript
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// vault keeps a directory of Markdown notes, such as an Obsidian vault, in
+// sync with the transcripts the service fetches or ingests.
+type vault struct {
+	dir  string
+	tags []string
+}
+
+// SetVault writes a Markdown note with YAML front matter into dir for every
+// transcript fetched or ingested from now on. Notes are named after the video
+// title and ID and overwritten when the transcript is fetched again. An empty
+// dir disables the vault.
+func (s *Service) SetVault(dir string, tags []string) error {
+	if dir == "" {
+		s.vault = nil
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create vault directory: %w", err)
+	}
+	s.vault = &vault{dir: dir, tags: tags}
+	return nil
+}
+
+// write stores the note for videoID. Failures are logged rather than
+// returned since the vault must not affect responses.
+func (v *vault) write(logger *slog.Logger, videoID string, resp *youtube.TranscriptResponse) {
+	if v == nil || resp == nil || resp.Raw == nil {
+		return
+	}
+
+	meta := format.NoteMeta{
+		Title:    resp.Title,
+		Channel:  resp.Channel,
+		Language: resp.Language,
+		Date:     time.Now(),
+		Tags:     v.tags,
+	}
+	if resp.Source != "upload" {
+		meta.URL = "https://www.youtube.com/watch?v=" + videoID
+	}
+	body := format.Note(resp.Raw.Segments, meta, DefaultIntervalSeconds)
+
+	path := filepath.Join(v.dir, noteFileName(videoID, resp.Title))
+	if err := writeFileAtomic(path, []byte(body)); err != nil {
+		logger.Warn("Failed to write vault note", "video_id", videoID, "path", path, "error", err)
+		return
+	}
+	logger.Debug("Wrote vault note", "video_id", videoID, "path", path)
+}
+
+// noteFileName builds "<title> (<id>).md" without characters that are
+// invalid in file names or Obsidian links.
+func noteFileName(videoID, title string) string {
+	title = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', '#', '^', '[', ']':
+			return -1
+		}
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+	if len([]rune(title)) > 100 {
+		title = string([]rune(title)[:100])
+	}
+	if title == "" {
+		return videoID + ".md"
+	}
+	return fmt.Sprintf("%s (%s).md", title, videoID)
+}
+
+// writeFileAtomic replaces path via a temporary file so that sync tools never
+// see a partially written note.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".note-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// delete removes the notes of videoID, whatever title they were written
+// with, and returns how many were removed.
+func (v *vault) delete(videoID string) (int, error) {
+	if v == nil {
+		return 0, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(v.dir, "* ("+videoID+").md"))
+	if err != nil {
+		return 0, err
+	}
+	matches = append(matches, filepath.Join(v.dir, noteFileName(videoID, "")))
+
+	removed := 0
+	for _, path := range matches {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("remove vault note: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,237 @@
+package transcript
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+const (
+	// DefaultWatchInterval is how often the watch folder is scanned
+	DefaultWatchInterval = 10 * time.Second
+	// watchProcessedDir and watchFailedDir receive handled files
+	watchProcessedDir = "processed"
+	watchFailedDir    = "failed"
+)
+
+// fileState is the size and modification time of a file in the watch
+// folder, compared between scans to tell whether it is still being written
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watcher polls a drop folder and processes files that stopped changing
+type watcher struct {
+	svc  *Service
+	dir  string
+	seen map[string]fileState
+}
+
+// Watch processes files dropped into dir until ctx is done, scanning every
+// interval:
+//
+//   - "<videoId>.<ext>" and "<videoId>.<lang>.<ext>" subtitle files are
+//     ingested for that video
+//   - yt-dlp "<name>.info.json" files are ingested with their subtitles
+//   - ".urls" and ".txt" files list video URLs or IDs, one per line, which
+//     are fetched and cached
+//
+// Files are processed once their size and modification time are unchanged
+// between two scans and then moved to the processed or failed subfolder.
+// Other files are left alone.
+func (s *Service) Watch(ctx context.Context, dir string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	for _, sub := range []string{watchProcessedDir, watchFailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return fmt.Errorf("create watch folder: %w", err)
+		}
+	}
+
+	w := &watcher{svc: s, dir: dir, seen: make(map[string]fileState)}
+	s.logger.Info("Watching folder", "dir", dir, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.scan(ctx); err != nil {
+			s.logger.Warn("Failed to scan watch folder", "dir", dir, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan processes the files that did not change since the previous scan.
+func (w *watcher) scan(ctx context.Context) error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]fileState, len(entries))
+	stable := make(map[string]bool)
+	var names, infos []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		seen[name] = state
+		names = append(names, name)
+		if prev, ok := w.seen[name]; ok && prev == state {
+			stable[name] = true
+		}
+		if strings.HasSuffix(name, ytDlpInfoSuffix) {
+			infos = append(infos, name)
+		}
+	}
+	w.seen = seen
+
+	// Files sharing the name of an info.json are processed together with it
+	// once all of them are complete
+	grouped := make(map[string]bool)
+	for _, info := range infos {
+		prefix := strings.TrimSuffix(info, ytDlpInfoSuffix) + "."
+		group, complete := []string{info}, stable[info]
+		for _, name := range names {
+			if name != info && strings.HasPrefix(name, prefix) {
+				group = append(group, name)
+				complete = complete && stable[name]
+			}
+		}
+		for _, name := range group {
+			grouped[name] = true
+		}
+		if complete && ctx.Err() == nil {
+			videoID, err := w.svc.importYtDlpFile(ctx, filepath.Join(w.dir, info))
+			w.finish(group, videoID, err)
+		}
+	}
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if stable[name] && !grouped[name] {
+			w.processFile(ctx, name)
+		}
+	}
+	return nil
+}
+
+func (w *watcher) processFile(ctx context.Context, name string) {
+	var (
+		videoID string
+		err     error
+	)
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".urls" || ext == ".txt":
+		err = w.fetchList(ctx, filepath.Join(w.dir, name))
+	case format.CanParse(name):
+		videoID, err = w.ingestSubtitle(ctx, name)
+	default:
+		return
+	}
+	w.finish([]string{name}, videoID, err)
+}
+
+// ingestSubtitle stores a subtitle file named after its video ID and,
+// optionally, its language.
+func (w *watcher) ingestSubtitle(ctx context.Context, name string) (string, error) {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	lang := subtitleLanguage(name)
+	videoID := strings.TrimSuffix(stem, "."+lang)
+	if lang == "" {
+		videoID = stem
+	}
+	if !videoIDPattern.MatchString(videoID) {
+		return "", fmt.Errorf("file name %q does not start with a video id", name)
+	}
+
+	segments, err := parseSubtitleFile(filepath.Join(w.dir, name))
+	if err != nil {
+		return "", err
+	}
+	if lang == "" {
+		_, err = w.svc.Ingest(ctx, videoID, "", segments)
+		return videoID, err
+	}
+	return videoID, w.svc.IngestYtDlp(ctx, YtDlpInfo{ID: videoID}, map[string][]youtube.TranscriptSegment{lang: segments})
+}
+
+// fetchList fetches and caches every video listed in the file at path,
+// skipping blank lines and # comments. The error lists the lines that
+// failed.
+func (w *watcher) fetchList(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var failed []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		req := TranscriptRequest{VideoURL: line}
+		if videoIDPattern.MatchString(line) {
+			req = TranscriptRequest{VideoID: line}
+		}
+		if _, err := w.svc.GetTranscripts(ctx, req); err != nil {
+			w.svc.logger.Warn("Failed to fetch listed video", "file", path, "line", line, "error", err)
+			failed = append(failed, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to fetch %d videos: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// finish moves handled files to the processed or failed subfolder.
+func (w *watcher) finish(files []string, videoID string, err error) {
+	target := watchProcessedDir
+	switch {
+	case errors.Is(err, ErrNoTranscript):
+		target = watchFailedDir
+		w.svc.logger.Warn("No transcript in watched file", "file", files[0])
+	case err != nil:
+		target = watchFailedDir
+		w.svc.logger.Warn("Failed to process watched file", "file", files[0], "error", err)
+	default:
+		w.svc.logger.Info("Processed watched file", "file", files[0], "video_id", videoID)
+	}
+
+	for _, name := range files {
+		if err := os.Rename(filepath.Join(w.dir, name), filepath.Join(w.dir, target, name)); err != nil {
+			w.svc.logger.Error("Failed to move watched file", "file", name, "error", err)
+		}
+		delete(w.seen, name)
+	}
+}
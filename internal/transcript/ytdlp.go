@@ -0,0 +1,209 @@
+package transcript
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+const (
+	// SourceYtDlp marks transcripts ingested from yt-dlp downloads
+	SourceYtDlp = "yt-dlp"
+	// ytDlpInfoSuffix names the metadata files written by --write-info-json
+	ytDlpInfoSuffix = ".info.json"
+	// maxInfoBytes bounds info.json files, which list every format of a video
+	maxInfoBytes = 32 << 20
+)
+
+// ErrInvalidInfo is returned for info.json files that are not yt-dlp video
+// metadata
+var ErrInvalidInfo = errors.New("invalid yt-dlp info.json")
+
+// YtDlpInfo holds the fields of a yt-dlp info.json used for ingestion
+type YtDlpInfo struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Channel  string `json:"channel"`
+	Uploader string `json:"uploader"`
+	Language string `json:"language"`
+	// Extractor is "youtube" for YouTube videos
+	Extractor string `json:"extractor"`
+}
+
+// ParseYtDlpInfo reads a yt-dlp info.json, requiring a YouTube video ID.
+func ParseYtDlpInfo(r io.Reader) (YtDlpInfo, error) {
+	var info YtDlpInfo
+	if err := json.NewDecoder(io.LimitReader(r, maxInfoBytes)).Decode(&info); err != nil {
+		return YtDlpInfo{}, fmt.Errorf("%w: %v", ErrInvalidInfo, err)
+	}
+	if info.Extractor != "" && !strings.HasPrefix(info.Extractor, "youtube") {
+		return YtDlpInfo{}, fmt.Errorf("%w: extractor %q is not youtube", ErrInvalidInfo, info.Extractor)
+	}
+	if !videoIDPattern.MatchString(info.ID) {
+		return YtDlpInfo{}, fmt.Errorf("%w: %q is not a video id", ErrInvalidInfo, info.ID)
+	}
+	return info, nil
+}
+
+// subtitleLanguage returns the language of a yt-dlp subtitle file named
+// "<name>.<lang>.<ext>", or "" when the name carries none.
+func subtitleLanguage(fileName string) string {
+	base := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	lang := strings.TrimPrefix(filepath.Ext(base), ".")
+	if !languagePattern.MatchString(lang) {
+		return ""
+	}
+	return lang
+}
+
+// IngestYtDlp stores the subtitles of a video downloaded with yt-dlp, keyed
+// by language as if they had been fetched with that language requested. The
+// subtitles in the video's language, else English, else the first language,
+// are also served by default. Every key of subtitles must be a language.
+func (s *Service) IngestYtDlp(ctx context.Context, info YtDlpInfo, subtitles map[string][]youtube.TranscriptSegment) error {
+	langs := make([]string, 0, len(subtitles))
+	for lang, segments := range subtitles {
+		if len(segments) > 0 {
+			langs = append(langs, lang)
+		}
+	}
+	if len(langs) == 0 {
+		return ErrNoTranscript
+	}
+	slices.Sort(langs)
+
+	preferred := langs[0]
+	for _, lang := range []string{info.Language, "en"} {
+		if lang != "" && slices.Contains(langs, lang) {
+			preferred = lang
+			break
+		}
+	}
+
+	for _, lang := range langs {
+		transcript := &youtube.TranscriptResponse{
+			Title:    info.Title,
+			Channel:  cmp.Or(info.Channel, info.Uploader),
+			Language: lang,
+			Source:   SourceYtDlp,
+			Raw:      &youtube.Transcript{Segments: subtitles[lang]},
+		}
+		s.index(info.ID, transcript)
+		key := TranscriptRequest{VideoID: info.ID, Language: lang}.cacheKey()
+		if err := s.repo.Save(ctx, key, transcript); err != nil {
+			return err
+		}
+		if lang != preferred {
+			continue
+		}
+		if err := s.repo.Save(ctx, info.ID, transcript); err != nil {
+			return err
+		}
+		s.vault.write(s.logger, info.ID, transcript)
+	}
+
+	s.logger.Info("Ingested yt-dlp transcript", "video_id", info.ID, "languages", langs)
+	return nil
+}
+
+// ImportResult lists the outcome of a yt-dlp directory import
+type ImportResult struct {
+	// Imported are the IDs of the videos stored
+	Imported []string `json:"imported"`
+	// Failed maps info.json files that could not be imported to the reason
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// ImportYtDlpDir ingests every video in dir that yt-dlp downloaded with
+// --write-info-json and --write-subs, i.e. "<name>.info.json" next to
+// "<name>.<lang>.vtt" (or .srt, .ttml) files. Subdirectories are searched
+// too, videos without subtitles are skipped.
+func (s *Service) ImportYtDlpDir(ctx context.Context, dir string) (ImportResult, error) {
+	result := ImportResult{Imported: []string{}, Failed: make(map[string]string)}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ytDlpInfoSuffix) {
+			return nil
+		}
+
+		videoID, err := s.importYtDlpFile(ctx, path)
+		switch {
+		case errors.Is(err, ErrNoTranscript):
+		case err != nil:
+			result.Failed[path] = err.Error()
+		default:
+			result.Imported = append(result.Imported, videoID)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("import yt-dlp directory: %w", err)
+	}
+	return result, nil
+}
+
+// importYtDlpFile ingests the video described by the info.json at path
+// together with the subtitle files next to it.
+func (s *Service) importYtDlpFile(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := ParseYtDlpInfo(file)
+	file.Close()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// Subtitles are named "<name>.<lang>.<ext>"
+	prefix := strings.TrimSuffix(filepath.Base(path), ytDlpInfoSuffix) + "."
+	subtitles := make(map[string][]youtube.TranscriptSegment)
+	for _, entry := range entries {
+		name := entry.Name()
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || entry.IsDir() || strings.Count(rest, ".") != 1 || !format.CanParse(name) {
+			continue
+		}
+		subtitlePath := filepath.Join(dir, name)
+		segments, err := parseSubtitleFile(subtitlePath)
+		if err != nil {
+			s.logger.Warn("Skipping unreadable subtitle file", "file", subtitlePath, "error", err)
+			continue
+		}
+		if lang := subtitleLanguage(name); lang != "" {
+			subtitles[lang] = segments
+		}
+	}
+
+	return info.ID, s.IngestYtDlp(ctx, info, subtitles)
+}
+
+func parseSubtitleFile(path string) ([]youtube.TranscriptSegment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return format.Parse(filepath.Base(path), file)
+}
@@ -0,0 +1,80 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// BilingualFormats are the exporters that support interleaving two languages
+var BilingualFormats = []string{"txt", "srt", "vtt"}
+
+// BilingualSegment is a primary segment with the text of the secondary
+// language aligned to it
+type BilingualSegment struct {
+	youtube.TranscriptSegment
+	Translation string
+}
+
+// Interleave aligns secondary segments to primary ones by timestamp. A
+// secondary segment is aligned to the primary segment containing its
+// midpoint, or the nearest one before it.
+func Interleave(primary, secondary []youtube.TranscriptSegment) []BilingualSegment {
+	aligned := make([][]string, len(primary))
+	p := 0
+	for _, segment := range secondary {
+		mid := segment.StartTime + segment.Duration/2
+		for p+1 < len(primary) && primary[p+1].StartTime <= mid {
+			p++
+		}
+		if len(primary) > 0 {
+			aligned[p] = append(aligned[p], segment.Text)
+		}
+	}
+
+	interleaved := make([]BilingualSegment, len(primary))
+	for i, segment := range primary {
+		interleaved[i] = BilingualSegment{
+			TranscriptSegment: segment,
+			Translation:       strings.Join(aligned[i], " "),
+		}
+	}
+	return interleaved
+}
+
+// RenderBilingual renders segments as produced by Interleave in one of
+// BilingualFormats. Text output puts the translation below each timestamped
+// line, subtitles show it as the cue's last line.
+func RenderBilingual(name string, segments []BilingualSegment, opts Options) (string, bool) {
+	switch name {
+	case "txt":
+		var b strings.Builder
+		for _, segment := range segments {
+			fmt.Fprintf(&b, "%s\n", timeText(segment.StartTime, segment.Text))
+			if segment.Translation != "" {
+				fmt.Fprintf(&b, "%s\n", segment.Translation)
+			}
+			b.WriteString("\n")
+		}
+		return b.String(), true
+	case "srt":
+		return SRT(cueSegments(segments), opts), true
+	case "vtt":
+		return VTT(cueSegments(segments), opts), true
+	}
+	return "", false
+}
+
+// cueSegments joins each segment's text and translation into the lines of a
+// subtitle cue.
+func cueSegments(segments []BilingualSegment) []youtube.TranscriptSegment {
+	cues := make([]youtube.TranscriptSegment, len(segments))
+	for i, segment := range segments {
+		cues[i] = segment.TranscriptSegment
+		if segment.Translation != "" {
+			cues[i].Text += "\n" + segment.Translation
+		}
+	}
+	return cues
+}
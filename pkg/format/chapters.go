@@ -0,0 +1,57 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// ChapterGroup is the transcript text of one chapter
+type ChapterGroup struct {
+	youtube.Chapter
+	Text string
+}
+
+// ChapterGroups groups segments by the chapter they start in. Chapters
+// without segments, e.g. outside a requested time range, are left out.
+// Segments before the first chapter belong to it.
+func ChapterGroups(segments []youtube.TranscriptSegment, chapters []youtube.Chapter) []ChapterGroup {
+	if len(segments) == 0 || len(chapters) == 0 {
+		return nil
+	}
+
+	texts := make([]strings.Builder, len(chapters))
+	current := 0
+	for _, segment := range segments {
+		for current+1 < len(chapters) && segment.StartTime >= chapters[current+1].Start {
+			current++
+		}
+		if texts[current].Len() > 0 {
+			texts[current].WriteString(" ")
+		}
+		texts[current].WriteString(segment.Text)
+	}
+
+	var groups []ChapterGroup
+	for i, chapter := range chapters {
+		if texts[i].Len() > 0 {
+			groups = append(groups, ChapterGroup{Chapter: chapter, Text: texts[i].String()})
+		}
+	}
+	return groups
+}
+
+// ChaptersStyled renders one line per chapter, prefixed with the timestamp
+// of the chapter in style and its title, e.g. "(01:05) Setup: text".
+func ChaptersStyled(segments []youtube.TranscriptSegment, chapters []youtube.Chapter, style TimestampStyle) []string {
+	groups := ChapterGroups(segments, chapters)
+	if groups == nil {
+		return nil
+	}
+
+	formatted := make([]string, len(groups))
+	for i, group := range groups {
+		formatted[i] = style.line(group.Start, group.Title+": "+group.Text)
+	}
+	return formatted
+}
@@ -0,0 +1,61 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+var (
+	// soundTagPattern matches caption annotations like [Music], [Applause]
+	// and music note runs
+	soundTagPattern = regexp.MustCompile(`\[[^\]]*\]|♪+`)
+	// fillerPattern matches common English filler words with a trailing comma
+	fillerPattern = regexp.MustCompile(`(?i)\b(?:u+m+|u+h+m*|e+r+m+|a+h+|h+m+|mhm|you know)\b,?`)
+	spacePattern  = regexp.MustCompile(`\s{2,}`)
+)
+
+// CleanOptions selects which kinds of noise Clean removes.
+type CleanOptions struct {
+	// SoundTags removes bracketed annotations such as [Music] or [Laughter].
+	SoundTags bool
+	// Fillers removes filler words such as "um", "uh" and "you know".
+	Fillers bool
+}
+
+// Enabled reports whether any cleanup is selected.
+func (o CleanOptions) Enabled() bool {
+	return o.SoundTags || o.Fillers
+}
+
+// Clean returns a copy of segments with the selected noise removed, dropping
+// segments that end up empty, along with the number of removed tokens.
+func Clean(segments []youtube.TranscriptSegment, opts CleanOptions) ([]youtube.TranscriptSegment, int) {
+	if !opts.Enabled() {
+		return segments, 0
+	}
+
+	cleaned := make([]youtube.TranscriptSegment, 0, len(segments))
+	removed := 0
+	for _, segment := range segments {
+		text := segment.Text
+		if opts.SoundTags {
+			removed += len(soundTagPattern.FindAllStringIndex(text, -1))
+			text = soundTagPattern.ReplaceAllString(text, " ")
+		}
+		if opts.Fillers {
+			removed += len(fillerPattern.FindAllStringIndex(text, -1))
+			text = fillerPattern.ReplaceAllString(text, " ")
+		}
+
+		text = strings.TrimSpace(spacePattern.ReplaceAllString(text, " "))
+		if text == "" {
+			continue
+		}
+		segment.Text = text
+		cleaned = append(cleaned, segment)
+	}
+
+	return cleaned, removed
+}
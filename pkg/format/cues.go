@@ -0,0 +1,129 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Defaults follow common subtitle guidelines: two lines of at most 42
+// characters, shown for at least a second and at most 7 seconds, at a reading
+// speed of no more than 20 characters per second.
+const (
+	DefaultMinCueDuration = 1.0
+	DefaultMaxCueDuration = 7.0
+	DefaultMaxCueChars    = 84
+	DefaultMaxCPS         = 20.0
+	DefaultLineLength     = 42
+
+	// maxMergeGap is the largest pause between segments bridged by merging
+	maxMergeGap = 1.5
+)
+
+// CueOptions controls how MergeCues joins short segments into readable cues.
+// Zero fields use the defaults.
+type CueOptions struct {
+	MinDuration float64
+	MaxDuration float64
+	MaxChars    int
+	// MaxCPS is the reading speed limit in characters per second
+	MaxCPS     float64
+	LineLength int
+}
+
+func (o CueOptions) withDefaults() CueOptions {
+	if o.MinDuration <= 0 {
+		o.MinDuration = DefaultMinCueDuration
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = DefaultMaxCueDuration
+	}
+	if o.MaxChars <= 0 {
+		o.MaxChars = DefaultMaxCueChars
+	}
+	if o.MaxCPS <= 0 {
+		o.MaxCPS = DefaultMaxCPS
+	}
+	if o.LineLength <= 0 {
+		o.LineLength = DefaultLineLength
+	}
+	return o
+}
+
+// MergeCues joins consecutive segments while the current cue is shorter than
+// the minimum duration or faster than the reading speed limit, as long as the
+// merged cue stays within the character and duration limits. Cue text is
+// wrapped to the configured line length.
+func MergeCues(segments []youtube.TranscriptSegment, opts CueOptions) []youtube.TranscriptSegment {
+	opts = opts.withDefaults()
+
+	merged := make([]youtube.TranscriptSegment, 0, len(segments))
+	var current youtube.TranscriptSegment
+	hasCurrent := false
+
+	for _, segment := range segments {
+		text := strings.Join(strings.Fields(segment.Text), " ")
+		if text == "" {
+			continue
+		}
+		segment.Text = text
+
+		if !hasCurrent {
+			current, hasCurrent = segment, true
+			continue
+		}
+
+		currentEnd := current.StartTime + current.Duration
+		mergedText := current.Text + " " + segment.Text
+		mergedDuration := segment.StartTime + segment.Duration - current.StartTime
+
+		needsMore := current.Duration < opts.MinDuration ||
+			float64(len(current.Text))/max(current.Duration, 0.001) > opts.MaxCPS
+		fits := len(mergedText) <= opts.MaxChars &&
+			mergedDuration <= opts.MaxDuration &&
+			segment.StartTime-currentEnd <= maxMergeGap
+
+		if needsMore && fits {
+			current.Text = mergedText
+			current.Duration = mergedDuration
+			continue
+		}
+
+		merged = append(merged, current)
+		current = segment
+	}
+	if hasCurrent {
+		merged = append(merged, current)
+	}
+
+	for i := range merged {
+		merged[i].Text = wrapLines(merged[i].Text, opts.LineLength)
+	}
+	return merged
+}
+
+// wrapLines breaks text at word boundaries into lines of at most width
+// characters where possible.
+func wrapLines(text string, width int) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	lineLen := 0
+	for i, word := range words {
+		if i > 0 {
+			if lineLen+1+len(word) > width {
+				b.WriteString("\n")
+				lineLen = 0
+			} else {
+				b.WriteString(" ")
+				lineLen++
+			}
+		}
+		b.WriteString(word)
+		lineLen += len(word)
+	}
+	return b.String()
+}
@@ -0,0 +1,226 @@
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Options controls how a transcript document is rendered.
+type Options struct {
+	// Title is written as a heading by formats that support one.
+	Title string
+	// IntervalSeconds is the grouping interval of text based formats.
+	IntervalSeconds float64
+	// OffsetSeconds shifts subtitle cue times, after scaling.
+	OffsetSeconds float64
+	// TimeScale stretches subtitle cue times, 1 when zero.
+	TimeScale float64
+	// Cues, when set, merges short segments into readable subtitle cues.
+	Cues *CueOptions
+	// VideoID and Language are included per line by JSONL when Metadata is
+	// set.
+	VideoID  string
+	Language string
+	Metadata bool
+}
+
+// Exporter renders a whole transcript as a single document.
+type Exporter struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Render      func(segments []youtube.TranscriptSegment, opts Options) string
+}
+
+var exporters = map[string]Exporter{
+	"txt":   {Name: "txt", ContentType: "text/plain; charset=utf-8", Extension: ".txt", Render: Text},
+	"md":    {Name: "md", ContentType: "text/markdown; charset=utf-8", Extension: ".md", Render: Markdown},
+	"srt":   {Name: "srt", ContentType: "application/x-subrip; charset=utf-8", Extension: ".srt", Render: SRT},
+	"vtt":   {Name: "vtt", ContentType: "text/vtt; charset=utf-8", Extension: ".vtt", Render: VTT},
+	"csv":   {Name: "csv", ContentType: "text/csv; charset=utf-8", Extension: ".csv", Render: CSV},
+	"tsv":   {Name: "tsv", ContentType: "text/tab-separated-values; charset=utf-8", Extension: ".tsv", Render: TSV},
+	"jsonl": {Name: "jsonl", ContentType: "application/jsonl; charset=utf-8", Extension: ".jsonl", Render: JSONL},
+}
+
+// Lookup returns the exporter registered under name.
+func Lookup(name string) (Exporter, bool) {
+	exporter, ok := exporters[strings.ToLower(name)]
+	return exporter, ok
+}
+
+// Text renders interval groups one per line.
+func Text(segments []youtube.TranscriptSegment, opts Options) string {
+	var b strings.Builder
+	for _, line := range Interval(segments, opts.IntervalSeconds) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Markdown renders a title heading followed by one paragraph per interval
+// group.
+func Markdown(segments []youtube.TranscriptSegment, opts Options) string {
+	var b strings.Builder
+	if opts.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", opts.Title)
+	}
+	for _, line := range Interval(segments, opts.IntervalSeconds) {
+		b.WriteString(line)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// Retime scales and then shifts segment times, e.g. to match a trimmed or
+// re-encoded copy of the video. Segments that end before zero are dropped and
+// segments starting before zero are clipped.
+func Retime(segments []youtube.TranscriptSegment, offsetSeconds, scale float64) []youtube.TranscriptSegment {
+	if scale == 0 {
+		scale = 1
+	}
+	if offsetSeconds == 0 && scale == 1 {
+		return segments
+	}
+
+	retimed := make([]youtube.TranscriptSegment, 0, len(segments))
+	for _, segment := range segments {
+		start := segment.StartTime*scale + offsetSeconds
+		end := (segment.StartTime+segment.Duration)*scale + offsetSeconds
+		if end <= 0 {
+			continue
+		}
+		start = max(start, 0)
+
+		segment.StartTime = start
+		segment.Duration = end - start
+		retimed = append(retimed, segment)
+	}
+	return retimed
+}
+
+// subtitleCues applies the cue merging and retiming selected in opts
+func subtitleCues(segments []youtube.TranscriptSegment, opts Options) []youtube.TranscriptSegment {
+	if opts.Cues != nil {
+		segments = MergeCues(segments, *opts.Cues)
+	}
+	return Retime(segments, opts.OffsetSeconds, opts.TimeScale)
+}
+
+// SRT renders segments as SubRip cues, merged and retimed per opts.
+func SRT(segments []youtube.TranscriptSegment, opts Options) string {
+	segments = subtitleCues(segments, opts)
+
+	var b strings.Builder
+	for i, segment := range segments {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n",
+			i+1,
+			cueTime(segment.StartTime, ','),
+			cueTime(segment.StartTime+segment.Duration, ','),
+			segment.Text,
+		)
+	}
+	return b.String()
+}
+
+// VTT renders segments as a WebVTT document, merged and retimed per opts.
+func VTT(segments []youtube.TranscriptSegment, opts Options) string {
+	segments = subtitleCues(segments, opts)
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, segment := range segments {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n",
+			cueTime(segment.StartTime, '.'),
+			cueTime(segment.StartTime+segment.Duration, '.'),
+			segment.Text,
+		)
+	}
+	return b.String()
+}
+
+// CSV renders one start,duration,end,text row per segment after a header row.
+func CSV(segments []youtube.TranscriptSegment, _ Options) string {
+	return delimited(segments, ',')
+}
+
+// TSV renders the same rows as CSV, separated by tabs.
+func TSV(segments []youtube.TranscriptSegment, _ Options) string {
+	return delimited(segments, '\t')
+}
+
+func delimited(segments []youtube.TranscriptSegment, comma rune) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Comma = comma
+
+	// Writing to a strings.Builder cannot fail
+	_ = w.Write([]string{"start", "duration", "end", "text"})
+	for _, segment := range segments {
+		_ = w.Write([]string{
+			secondsText(segment.StartTime),
+			secondsText(segment.Duration),
+			secondsText(segment.StartTime + segment.Duration),
+			segment.Text,
+		})
+	}
+	w.Flush()
+	return b.String()
+}
+
+// jsonlLine is a single JSONL record. Metadata fields are omitted unless
+// requested.
+type jsonlLine struct {
+	VideoID  string  `json:"videoId,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	Language string  `json:"language,omitempty"`
+	Index    int     `json:"index"`
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+	End      float64 `json:"end"`
+	Text     string  `json:"text"`
+}
+
+// JSONL renders one JSON object per segment and line. With opts.Metadata
+// each line also carries the video ID, title and language.
+func JSONL(segments []youtube.TranscriptSegment, opts Options) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	for i, segment := range segments {
+		line := jsonlLine{
+			Index:    i,
+			Start:    segment.StartTime,
+			Duration: segment.Duration,
+			End:      segment.StartTime + segment.Duration,
+			Text:     segment.Text,
+		}
+		if opts.Metadata {
+			line.VideoID = opts.VideoID
+			line.Title = opts.Title
+			line.Language = opts.Language
+		}
+		// Segments always encode
+		_ = enc.Encode(line)
+	}
+	return b.String()
+}
+
+// secondsText formats a time with millisecond precision
+func secondsText(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
+
+// cueTime formats seconds as hh:mm:ss followed by sep and milliseconds.
+func cueTime(seconds float64, sep byte) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,147 @@
+// Package format renders transcript segments into grouped text and subtitle
+// documents without requiring a youtube.Client.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Group is a block of transcript text starting at Start seconds.
+type Group struct {
+	Start float64
+	Text  string
+}
+
+// Interval groups segments into blocks that start at least intervalSeconds
+// apart. Each block is prefixed with the timestamp of its first segment.
+func Interval(segments []youtube.TranscriptSegment, intervalSeconds float64) []string {
+	return IntervalStyled(segments, intervalSeconds, DefaultTimestampStyle)
+}
+
+// IntervalStyled groups segments like Interval with timestamps laid out in
+// style.
+func IntervalStyled(segments []youtube.TranscriptSegment, intervalSeconds float64, style TimestampStyle) []string {
+	groups := IntervalGroups(segments, intervalSeconds)
+	if groups == nil {
+		return nil
+	}
+
+	formatted := make([]string, len(groups))
+	for i, group := range groups {
+		formatted[i] = style.line(group.Start, group.Text)
+	}
+	return formatted
+}
+
+// IntervalGroups groups segments like Interval but keeps start times and text
+// apart, for renderers that lay out timestamps themselves.
+func IntervalGroups(segments []youtube.TranscriptSegment, intervalSeconds float64) []Group {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	var groups []Group
+	currentStart := segments[0].StartTime
+	var groupText strings.Builder
+
+	for _, segment := range segments {
+		if segment.StartTime-currentStart >= intervalSeconds && groupText.Len() > 0 {
+			groups = append(groups, Group{Start: currentStart, Text: groupText.String()})
+			currentStart = segment.StartTime
+			groupText.Reset()
+		}
+		if groupText.Len() > 0 {
+			groupText.WriteString(" ")
+		}
+		groupText.WriteString(segment.Text)
+	}
+
+	if groupText.Len() > 0 {
+		groups = append(groups, Group{Start: currentStart, Text: groupText.String()})
+	}
+
+	return groups
+}
+
+// Sentences regroups segments into sentences terminated by '.', '!' or '?'.
+// Each sentence is prefixed with the timestamp of the segment it starts in.
+func Sentences(segments []youtube.TranscriptSegment) []string {
+	var sentences []string
+	var current strings.Builder
+	currentStart := 0.0
+
+	for _, segment := range segments {
+		for _, word := range strings.Fields(segment.Text) {
+			if current.Len() == 0 {
+				currentStart = segment.StartTime
+			} else {
+				current.WriteString(" ")
+			}
+			current.WriteString(word)
+
+			if strings.ContainsAny(word[len(word)-1:], ".!?") {
+				sentences = append(sentences, timeText(currentStart, current.String()))
+				current.Reset()
+			}
+		}
+	}
+
+	if current.Len() > 0 {
+		sentences = append(sentences, timeText(currentStart, current.String()))
+	}
+
+	return sentences
+}
+
+// Paragraphs joins every sentencesPerParagraph sentences into one paragraph
+// prefixed with the timestamp of its first sentence.
+func Paragraphs(segments []youtube.TranscriptSegment, sentencesPerParagraph int) []string {
+	if sentencesPerParagraph <= 0 {
+		sentencesPerParagraph = 5
+	}
+
+	var paragraphs []string
+	var current strings.Builder
+	count := 0
+	for _, sentence := range Sentences(segments) {
+		if count == 0 {
+			current.WriteString(sentence)
+		} else {
+			// Drop the timestamp prefix of all but the first sentence
+			_, text, _ := strings.Cut(sentence, ") ")
+			current.WriteString(" ")
+			current.WriteString(text)
+		}
+
+		count++
+		if count == sentencesPerParagraph {
+			paragraphs = append(paragraphs, current.String())
+			current.Reset()
+			count = 0
+		}
+	}
+
+	if current.Len() > 0 {
+		paragraphs = append(paragraphs, current.String())
+	}
+
+	return paragraphs
+}
+
+// Timestamp formats seconds as mm:ss, or hh:mm:ss for times past an hour.
+func Timestamp(seconds float64) string {
+	hours := int(seconds / 3600)
+	minutes := int((seconds - float64(hours*3600)) / 60)
+	secs := int(seconds - float64(hours*3600+minutes*60))
+	if hours > 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, secs)
+	}
+	return fmt.Sprintf("%02d:%02d", minutes, secs)
+}
+
+func timeText(startTime float64, text string) string {
+	return DefaultTimestampStyle.line(startTime, text)
+}
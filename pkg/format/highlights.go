@@ -0,0 +1,75 @@
+package format
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Highlight is a moment of a video suggested for clipping
+type Highlight struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Timestamp is Start in the form of Timestamp
+	Timestamp string `json:"timestamp"`
+	Text      string `json:"text"`
+	// Score is the salience per second, comparable within one video only
+	Score float64 `json:"score"`
+}
+
+// Highlights splits the transcript into moments of about windowSeconds and
+// returns the count moments densest in salient words, as weighted by the
+// salience-ranked Reduce strategy, best first.
+func Highlights(segments []youtube.TranscriptSegment, count int, windowSeconds float64) []Highlight {
+	if len(segments) == 0 || count <= 0 {
+		return nil
+	}
+
+	// Salience is weighted across moments so that words spread over the
+	// whole video count less than those concentrated in a few moments
+	var moments [][]youtube.TranscriptSegment
+	start := 0
+	for i := range segments {
+		if i > start && segments[i].StartTime-segments[start].StartTime >= windowSeconds {
+			moments = append(moments, segments[start:i])
+			start = i
+		}
+	}
+	moments = append(moments, segments[start:])
+
+	words := make([][]string, len(moments))
+	for i, moment := range moments {
+		for _, segment := range moment {
+			words[i] = append(words[i], contentWords(segment.Text)...)
+		}
+	}
+	weights := salienceWeights(words)
+
+	highlights := make([]Highlight, len(moments))
+	for i, moment := range moments {
+		first, last := moment[0], moment[len(moment)-1]
+		texts := make([]string, len(moment))
+		for j, segment := range moment {
+			texts[j] = segment.Text
+		}
+		sum := 0.0
+		for _, word := range words[i] {
+			sum += weights[word]
+		}
+		end := last.StartTime + last.Duration
+		highlights[i] = Highlight{
+			Start:     first.StartTime,
+			End:       end,
+			Timestamp: Timestamp(first.StartTime),
+			Text:      strings.Join(texts, " "),
+			Score:     math.Round(sum/max(end-first.StartTime, 1)*1000) / 1000,
+		}
+	}
+
+	sort.SliceStable(highlights, func(a, b int) bool {
+		return highlights[a].Score > highlights[b].Score
+	})
+	return highlights[:min(count, len(highlights))]
+}
@@ -0,0 +1,50 @@
+package format
+
+import (
+	"embed"
+	"html/template"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+//go:embed templates/transcript.html
+var templateFS embed.FS
+
+var pageTemplate = template.Must(template.New("transcript.html").Funcs(template.FuncMap{
+	"timestamp": Timestamp,
+	"seconds":   func(s float64) int { return int(s) },
+}).ParseFS(templateFS, "templates/transcript.html"))
+
+// pageData is the input of the transcript page template
+type pageData struct {
+	Title    string
+	VideoID  string
+	Language string
+	// WatchURL links timestamps to the video, empty for uploaded transcripts
+	WatchURL string
+	Groups   []Group
+}
+
+// HTML renders a standalone, printable page with the title and the
+// timestamped transcript in interval groups. Timestamps link to the video
+// when opts.VideoID is a YouTube video.
+func HTML(segments []youtube.TranscriptSegment, opts Options) string {
+	data := pageData{
+		Title:    opts.Title,
+		VideoID:  opts.VideoID,
+		Language: opts.Language,
+		Groups:   IntervalGroups(segments, opts.IntervalSeconds),
+	}
+	if data.Title == "" {
+		data.Title = opts.VideoID
+	}
+	if opts.VideoID != "" && !strings.HasPrefix(opts.VideoID, "upload-") {
+		data.WatchURL = "https://www.youtube.com/watch?v=" + opts.VideoID
+	}
+
+	var b strings.Builder
+	// The template is fixed and its data always renders
+	_ = pageTemplate.Execute(&b, data)
+	return b.String()
+}
@@ -0,0 +1,152 @@
+package format
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+const (
+	// detectSampleBytes bounds how much text DetectLanguage looks at
+	detectSampleBytes = 20000
+	// minDetectWords is the fewest words a Latin script guess is made from
+	minDetectWords = 5
+)
+
+// LanguageGuess is the language a text is most likely written in
+type LanguageGuess struct {
+	// Language is an ISO 639-1 code, e.g. "en"
+	Language string `json:"language"`
+	// Confidence is between 0 and 1
+	Confidence float64 `json:"confidence"`
+}
+
+// stopwords holds frequent function words of Latin script languages. They are
+// short and common enough for a few sentences to tell languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "to", "of", "in", "that", "it", "you", "this", "for", "with", "have", "not", "but", "what", "they", "we"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "ama", "gibi", "ne", "daha", "olarak", "şey", "var", "yok", "ben", "sen", "biz", "mi"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "es", "ein", "eine", "zu", "mit", "auf", "den", "dem", "auch", "wir", "sind", "wie"},
+	"fr": {"le", "la", "les", "et", "est", "un", "une", "des", "du", "que", "qui", "pas", "pour", "dans", "ce", "il", "je", "vous", "nous", "sur"},
+	"es": {"el", "la", "los", "las", "y", "es", "que", "un", "una", "por", "para", "con", "no", "lo", "pero", "como", "muy", "esto", "está", "del"},
+	"it": {"il", "la", "di", "che", "e", "è", "un", "una", "per", "non", "sono", "gli", "con", "del", "della", "questo", "ma", "come", "anche", "mi"},
+	"pt": {"o", "a", "os", "as", "e", "é", "que", "um", "uma", "não", "para", "com", "do", "da", "em", "isso", "mas", "como", "você", "muito"},
+	"nl": {"de", "het", "een", "en", "is", "van", "dat", "niet", "ik", "je", "we", "op", "te", "met", "voor", "zijn", "maar", "ook", "dit", "wat"},
+}
+
+var stopwordLanguages = func() map[string][]string {
+	byWord := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, word := range words {
+			byWord[word] = append(byWord[word], lang)
+		}
+	}
+	return byWord
+}()
+
+// scripts identifies languages written in a script of their own
+var scripts = []struct {
+	language string
+	table    *unicode.RangeTable
+}{
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"hi", unicode.Devanagari},
+	{"ko", unicode.Hangul},
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"zh", unicode.Han},
+	{"el", unicode.Greek},
+	{"he", unicode.Hebrew},
+	{"th", unicode.Thai},
+}
+
+// DetectLanguage guesses the language of the transcript text. Languages with
+// a script of their own are told apart by script, Latin script languages by
+// their most frequent words. It returns false when the text is too short or
+// matches no known language.
+func DetectLanguage(segments []youtube.TranscriptSegment) (LanguageGuess, bool) {
+	var b strings.Builder
+	for _, segment := range segments {
+		if b.Len() >= detectSampleBytes {
+			break
+		}
+		b.WriteString(segment.Text)
+		b.WriteByte(' ')
+	}
+	text := strings.ToLower(b.String())
+
+	if guess, ok := detectScript(text); ok {
+		return guess, true
+	}
+
+	scores := make(map[string]float64)
+	words := 0
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		words++
+		langs := stopwordLanguages[word]
+		for _, lang := range langs {
+			// Words shared by several languages say less about each
+			scores[lang] += 1 / float64(len(langs))
+		}
+	}
+	if words < minDetectWords {
+		return LanguageGuess{}, false
+	}
+
+	var best, second string
+	for lang, score := range scores {
+		switch {
+		case best == "" || score > scores[best] || score == scores[best] && lang < best:
+			best, second = lang, best
+		case second == "" || score > scores[second] || score == scores[second] && lang < second:
+			second = lang
+		}
+	}
+	if best == "" {
+		return LanguageGuess{}, false
+	}
+
+	// Confidence grows with the lead over the runner-up and with how much of
+	// the text consists of stopwords at all
+	lead := (scores[best] - scores[second]) / scores[best]
+	coverage := min(scores[best]/float64(words)*4, 1)
+	return LanguageGuess{Language: best, Confidence: math.Round(lead*coverage*100) / 100}, true
+}
+
+// detectScript returns the language of a script making up most letters of
+// text, if any. Japanese text mixes kana with Han, so any kana means Japanese.
+func detectScript(text string) (LanguageGuess, bool) {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.language]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return LanguageGuess{}, false
+	}
+
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	for lang, count := range counts {
+		if share := float64(count) / float64(letters); share > 0.5 {
+			return LanguageGuess{Language: lang, Confidence: math.Round(share*100) / 100}, true
+		}
+	}
+	return LanguageGuess{}, false
+}
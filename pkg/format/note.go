@@ -0,0 +1,54 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// NoteMeta is written as YAML front matter by Note.
+type NoteMeta struct {
+	Title    string
+	Channel  string
+	URL      string
+	Language string
+	Date     time.Time
+	Tags     []string
+}
+
+// Note renders a Markdown document with YAML front matter, as used by
+// Obsidian and similar knowledge base tools, followed by the transcript in
+// interval groups.
+func Note(segments []youtube.TranscriptSegment, meta NoteMeta, intervalSeconds float64) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	writeYAMLField(&b, "title", meta.Title)
+	writeYAMLField(&b, "channel", meta.Channel)
+	writeYAMLField(&b, "url", meta.URL)
+	writeYAMLField(&b, "language", meta.Language)
+	if !meta.Date.IsZero() {
+		fmt.Fprintf(&b, "date: %s\n", meta.Date.Format(time.DateOnly))
+	}
+	if len(meta.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range meta.Tags {
+			fmt.Fprintf(&b, "  - %s\n", strconv.Quote(tag))
+		}
+	}
+	b.WriteString("---\n\n")
+
+	b.WriteString(Markdown(segments, Options{Title: meta.Title, IntervalSeconds: intervalSeconds}))
+	return b.String()
+}
+
+// writeYAMLField writes a double-quoted scalar, skipping empty values. Go
+// quoting produces escapes that are valid in YAML double-quoted strings.
+func writeYAMLField(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "%s: %s\n", key, strconv.Quote(value))
+}
@@ -0,0 +1,194 @@
+package format
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// cueTimePartPattern matches the fields of a cue time, rejecting signs,
+// exponents and special values that strconv.ParseFloat accepts
+var cueTimePartPattern = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+
+// cueTagPattern matches inline cue markup such as <i>, <c.colorE5E5E5> or
+// karaoke timestamps like <00:00:01.000>.
+var cueTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// CanParse reports whether Parse supports the format named by name.
+func CanParse(name string) bool {
+	switch parseKind(name) {
+	case "srt", "vtt", "webvtt", "ttml", "xml", "dfxp":
+		return true
+	default:
+		return false
+	}
+}
+
+func parseKind(name string) string {
+	kind := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if kind == "" {
+		kind = strings.ToLower(name)
+	}
+	return kind
+}
+
+// Parse reads a subtitle document in the format named by name, which may be a
+// bare format ("srt", "vtt", "ttml") or a file name with that extension.
+// Segments are returned ordered by start time.
+func Parse(name string, r io.Reader) ([]youtube.TranscriptSegment, error) {
+	var segments []youtube.TranscriptSegment
+	var err error
+	switch parseKind(name) {
+	case "srt":
+		segments, err = ParseSRT(r)
+	case "vtt", "webvtt":
+		segments, err = ParseVTT(r)
+	case "ttml", "xml", "dfxp":
+		segments, err = youtube.ParseTTML(r)
+	default:
+		return nil, fmt.Errorf("unsupported subtitle format %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(segments, func(a, b youtube.TranscriptSegment) int {
+		return cmp.Compare(a.StartTime, b.StartTime)
+	})
+	return segments, nil
+}
+
+// ParseSRT reads a SubRip document.
+func ParseSRT(r io.Reader) ([]youtube.TranscriptSegment, error) {
+	return parseCues(r, false)
+}
+
+// ParseVTT reads a WebVTT document. Cue settings, NOTE and STYLE blocks and
+// inline markup are ignored.
+func ParseVTT(r io.Reader) ([]youtube.TranscriptSegment, error) {
+	return parseCues(r, true)
+}
+
+// parseCues reads blank line separated cue blocks whose timing line contains
+// "-->". Lines before the timing line (SRT counters, VTT identifiers) are
+// skipped.
+func parseCues(r io.Reader, webVTT bool) ([]youtube.TranscriptSegment, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var segments []youtube.TranscriptSegment
+	var block []string
+	lineNo := 0
+
+	flush := func() error {
+		defer func() { block = block[:0] }()
+
+		timing := -1
+		for i, line := range block {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			// Headers, NOTE, STYLE and REGION blocks carry no cue
+			return nil
+		}
+
+		start, end, err := parseTiming(block[timing])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		lines := block[timing+1:]
+		text := strings.Join(lines, " ")
+		if webVTT {
+			text = cueTagPattern.ReplaceAllString(text, "")
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil
+		}
+
+		segments = append(segments, youtube.TranscriptSegment{
+			Text:      text,
+			StartTime: start,
+			Duration:  end - start,
+		})
+		return nil
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func parseTiming(line string) (float64, float64, error) {
+	startStr, rest, _ := strings.Cut(line, "-->")
+	// VTT cue settings follow the end time
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("invalid cue timing %q", line)
+	}
+
+	start, err := parseCueTime(strings.TrimSpace(startStr))
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseCueTime(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("cue ends before it starts in %q", line)
+	}
+	return start, end, nil
+}
+
+// parseCueTime parses hh:mm:ss,mmm, hh:mm:ss.mmm or mm:ss.mmm timestamps.
+func parseCueTime(value string) (float64, error) {
+	parts := strings.Split(strings.Replace(value, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid cue time %q", value)
+	}
+
+	total := 0.0
+	for _, part := range parts {
+		if !cueTimePartPattern.MatchString(part) {
+			return 0, fmt.Errorf("invalid cue time %q", value)
+		}
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cue time %q", value)
+		}
+		total = total*60 + n
+	}
+	return total, nil
+}
@@ -0,0 +1,188 @@
+package format
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Strategies for Reduce
+const (
+	ReduceHead     = "head"
+	ReduceHeadTail = "head+tail"
+	ReduceUniform  = "uniform-sample"
+	ReduceSalience = "salience-ranked"
+)
+
+// ReduceStrategies lists the strategies Reduce accepts
+var ReduceStrategies = []string{ReduceHead, ReduceHeadTail, ReduceUniform, ReduceSalience}
+
+// Reduce drops segments until the transcript fits budget tokens as estimated
+// with tokenizer. Kept segments stay in chronological order:
+//
+//   - head keeps the beginning
+//   - head+tail keeps the beginning and the end, half of the budget each
+//   - uniform-sample keeps evenly spaced segments across the whole video
+//   - salience-ranked keeps the segments richest in words that recur across
+//     the transcript without appearing everywhere, a cheap proxy for its
+//     main topics
+//
+// Unknown strategies behave like head.
+func Reduce(segments []youtube.TranscriptSegment, strategy string, budget int, tokenizer Tokenizer) []youtube.TranscriptSegment {
+	costs := make([]int, len(segments))
+	total := 0
+	for i := range segments {
+		// One more token for the separating space
+		costs[i] = EstimateTokens(segments[i:i+1], tokenizer).Tokens + 1
+		total += costs[i]
+	}
+	if total <= budget {
+		return segments
+	}
+
+	var keep []int
+	switch strategy {
+	case ReduceHeadTail:
+		keep = append(takeWithin(costs, budget/2, false), takeWithin(costs, budget-budget/2, true)...)
+	case ReduceUniform:
+		keep = sampleUniform(costs, budget)
+	case ReduceSalience:
+		keep = rankBySalience(segments, costs, budget)
+	default:
+		keep = takeWithin(costs, budget, false)
+	}
+
+	slices.Sort(keep)
+	keep = slices.Compact(keep)
+	reduced := make([]youtube.TranscriptSegment, 0, len(keep))
+	for _, i := range keep {
+		reduced = append(reduced, segments[i])
+	}
+	return reduced
+}
+
+// takeWithin returns the indices of leading, or with fromEnd trailing,
+// segments whose costs fit budget.
+func takeWithin(costs []int, budget int, fromEnd bool) []int {
+	var indices []int
+	used := 0
+	for n := range costs {
+		i := n
+		if fromEnd {
+			i = len(costs) - 1 - n
+		}
+		if used+costs[i] > budget {
+			break
+		}
+		used += costs[i]
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// sampleUniform picks evenly spaced segments, as many as the average cost
+// allows, skipping any that would exceed budget.
+func sampleUniform(costs []int, budget int) []int {
+	total := 0
+	for _, cost := range costs {
+		total += cost
+	}
+	count := len(costs) * budget / max(total, 1)
+	if count == 0 {
+		return nil
+	}
+
+	var indices []int
+	used := 0
+	step := float64(len(costs)) / float64(count)
+	for k := 0; k < count; k++ {
+		i := int(float64(k) * step)
+		if used+costs[i] > budget {
+			continue
+		}
+		used += costs[i]
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// rankBySalience scores segments by the mean salience weight of their words
+// and keeps the best scoring ones that fit budget.
+func rankBySalience(segments []youtube.TranscriptSegment, costs []int, budget int) []int {
+	words := make([][]string, len(segments))
+	for i, segment := range segments {
+		words[i] = contentWords(segment.Text)
+	}
+	weights := salienceWeights(words)
+
+	scores := make([]float64, len(segments))
+	order := make([]int, len(segments))
+	for i := range segments {
+		order[i] = i
+		if len(words[i]) == 0 {
+			continue
+		}
+		sum := 0.0
+		for _, word := range words[i] {
+			sum += weights[word]
+		}
+		scores[i] = sum / float64(len(words[i]))
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	var indices []int
+	used := 0
+	for _, i := range order {
+		if used+costs[i] > budget {
+			continue
+		}
+		used += costs[i]
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// salienceWeights weights the content words of each segment by how often
+// they occur and how few segments contain them.
+func salienceWeights(words [][]string) map[string]float64 {
+	count := make(map[string]int)
+	segmentCount := make(map[string]int)
+	for _, segmentWords := range words {
+		seen := make(map[string]bool)
+		for _, word := range segmentWords {
+			count[word]++
+			if !seen[word] {
+				seen[word] = true
+				segmentCount[word]++
+			}
+		}
+	}
+
+	n := float64(len(words))
+	weights := make(map[string]float64, len(count))
+	for word, c := range count {
+		weights[word] = math.Log1p(float64(c)) * math.Log(n/float64(segmentCount[word]))
+	}
+	return weights
+}
+
+// contentWords lowercases text and returns its words of four or more letters,
+// which skips most function words in English and similar languages.
+func contentWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	words := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) >= 4 {
+			words = append(words, field)
+		}
+	}
+	return words
+}
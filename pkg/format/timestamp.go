@@ -0,0 +1,82 @@
+package format
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxStyleLength bounds timestamp styles, which are user supplied
+const maxStyleLength = 64
+
+// TimestampStyle lays out the timestamp that prefixes formatted lines. It is
+// literal text with placeholders:
+//
+//   - {time} is mm:ss, or hh:mm:ss for times past an hour
+//   - {hh}, {mm} and {ss} are zero padded hours, minutes and seconds; {mm}
+//     counts all minutes when the style has no {hh}
+//   - {s} is the whole number of seconds
+//
+// For example "[{hh}:{mm}:{ss}]" renders "[00:01:05] text" and
+// "{mm}:{ss} →" renders "01:05 → text".
+type TimestampStyle string
+
+// DefaultTimestampStyle renders "(01:05) text"
+const DefaultTimestampStyle TimestampStyle = "({time})"
+
+var stylePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ParseTimestampStyle validates a style, returning DefaultTimestampStyle for
+// an empty one.
+func ParseTimestampStyle(style string) (TimestampStyle, error) {
+	if style == "" {
+		return DefaultTimestampStyle, nil
+	}
+	if len(style) > maxStyleLength {
+		return "", fmt.Errorf("timestamp style is longer than %d bytes", maxStyleLength)
+	}
+	placeholders := stylePlaceholder.FindAllString(style, -1)
+	if len(placeholders) == 0 {
+		return "", fmt.Errorf("timestamp style %q has no {time}, {hh}, {mm}, {ss} or {s} placeholder", style)
+	}
+	for _, p := range placeholders {
+		switch p {
+		case "{time}", "{hh}", "{mm}", "{ss}", "{s}":
+		default:
+			return "", fmt.Errorf("unknown placeholder %s in timestamp style, expected {time}, {hh}, {mm}, {ss} or {s}", p)
+		}
+	}
+	return TimestampStyle(style), nil
+}
+
+// Format renders the timestamp of seconds.
+func (st TimestampStyle) Format(seconds float64) string {
+	if st == "" {
+		st = DefaultTimestampStyle
+	}
+	total := int(seconds)
+	hours, minutes, secs := total/3600, total%3600/60, total%60
+	if !strings.Contains(string(st), "{hh}") {
+		minutes = total / 60
+	}
+	return stylePlaceholder.ReplaceAllStringFunc(string(st), func(p string) string {
+		switch p {
+		case "{time}":
+			return Timestamp(seconds)
+		case "{hh}":
+			return fmt.Sprintf("%02d", hours)
+		case "{mm}":
+			return fmt.Sprintf("%02d", minutes)
+		case "{ss}":
+			return fmt.Sprintf("%02d", secs)
+		case "{s}":
+			return fmt.Sprint(total)
+		}
+		return p
+	})
+}
+
+// line prefixes text with the timestamp of startTime
+func (st TimestampStyle) line(startTime float64, text string) string {
+	return st.Format(startTime) + " " + text
+}
@@ -0,0 +1,94 @@
+package format
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Tokenizer approximates a model family's tokenizer by average characters
+// per token. Estimates are typically within 10-15% for English text.
+type Tokenizer struct {
+	Name string
+	// CharsPerToken applies to ASCII text including whitespace
+	CharsPerToken float64
+	// RunesPerToken applies to other scripts, which tokenize less densely
+	RunesPerToken float64
+}
+
+// tokenizers maps model name prefixes to tokenizer approximations. The
+// longest matching prefix wins.
+var tokenizers = map[string]Tokenizer{
+	"gpt-4o":  {Name: "o200k", CharsPerToken: 4.2, RunesPerToken: 1.6},
+	"o1":      {Name: "o200k", CharsPerToken: 4.2, RunesPerToken: 1.6},
+	"o3":      {Name: "o200k", CharsPerToken: 4.2, RunesPerToken: 1.6},
+	"gpt-4":   {Name: "cl100k", CharsPerToken: 4.0, RunesPerToken: 1.2},
+	"gpt-3.5": {Name: "cl100k", CharsPerToken: 4.0, RunesPerToken: 1.2},
+	"claude":  {Name: "claude", CharsPerToken: 3.5, RunesPerToken: 1.2},
+	"llama":   {Name: "llama", CharsPerToken: 3.8, RunesPerToken: 1.3},
+	"mistral": {Name: "mistral", CharsPerToken: 3.5, RunesPerToken: 1.1},
+	"gemini":  {Name: "gemini", CharsPerToken: 4.0, RunesPerToken: 1.5},
+}
+
+// DefaultTokenModel is assumed when no model is requested
+const DefaultTokenModel = "gpt-4o"
+
+// TokenizerFor returns the tokenizer approximation for a model name such as
+// "gpt-4o-mini" or "claude-3-5-sonnet".
+func TokenizerFor(model string) (Tokenizer, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	best, found := "", false
+	for prefix := range tokenizers {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return tokenizers[best], found
+}
+
+// TokenModels lists the model name prefixes TokenizerFor understands
+func TokenModels() []string {
+	models := make([]string, 0, len(tokenizers))
+	for prefix := range tokenizers {
+		models = append(models, prefix)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// TokenEstimate is the estimated size of a transcript's text
+type TokenEstimate struct {
+	Characters int `json:"characters"`
+	Words      int `json:"words"`
+	Tokens     int `json:"tokens"`
+}
+
+// EstimateTokens estimates the tokens of the transcript text joined by
+// spaces, as it would be sent to a model.
+func EstimateTokens(segments []youtube.TranscriptSegment, tokenizer Tokenizer) TokenEstimate {
+	var estimate TokenEstimate
+	var ascii, other int
+	for i, segment := range segments {
+		if i > 0 {
+			ascii++
+		}
+		estimate.Words += len(strings.Fields(segment.Text))
+		for _, r := range segment.Text {
+			if r < utf8.RuneSelf {
+				ascii++
+			} else if !unicode.IsSpace(r) {
+				other++
+			}
+		}
+		estimate.Characters += utf8.RuneCountInString(segment.Text)
+	}
+	estimate.Characters += max(len(segments)-1, 0)
+
+	tokens := float64(ascii)/tokenizer.CharsPerToken + float64(other)/tokenizer.RunesPerToken
+	estimate.Tokens = int(math.Ceil(tokens))
+	return estimate
+}
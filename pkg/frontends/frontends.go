@@ -0,0 +1,55 @@
+// Package frontends provides caption sources that fetch transcripts through
+// an Invidious or Piped instance, for deployments where direct YouTube
+// access is blocked. Register them with youtube.RegisterSource and name
+// them in the source order.
+package frontends
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// maxResponseBytes bounds responses read from an instance
+const maxResponseBytes = 16 << 20
+
+// parseInstance validates an instance base URL such as
+// https://invidious.example.org
+func parseInstance(instance string) (*url.URL, error) {
+	base, err := url.Parse(strings.TrimRight(instance, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance url: %w", err)
+	}
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid instance url %q: must be an absolute http or https url", instance)
+	}
+	return base, nil
+}
+
+// get performs a GET request with the client's rate limited HTTP client and
+// returns the body of a 200 response.
+func get(ctx context.Context, c *youtube.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &youtube.StatusError{StatusCode: resp.StatusCode}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return body, nil
+}
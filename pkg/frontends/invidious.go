@@ -0,0 +1,108 @@
+package frontends
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// invidiousSource lists tracks with the Invidious /api/v1/captions endpoint
+// and downloads them as WebVTT
+type invidiousSource struct {
+	client *youtube.Client
+	base   *url.URL
+}
+
+// Invidious returns a factory for a caption source backed by the Invidious
+// instance at instance, e.g. https://invidious.example.org.
+func Invidious(instance string) (youtube.SourceFactory, error) {
+	base, err := parseInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *youtube.Client) youtube.CaptionSource {
+		return &invidiousSource{client: c, base: base}
+	}, nil
+}
+
+func (s *invidiousSource) Name() string {
+	return "invidious"
+}
+
+type invidiousCaptions struct {
+	Captions []struct {
+		Label        string `json:"label"`
+		LanguageCode string `json:"languageCode"`
+		URL          string `json:"url"`
+	} `json:"captions"`
+}
+
+func (s *invidiousSource) ListTracks(ctx context.Context, videoID string, _ youtube.RequestOptions) ([]youtube.CaptionTrack, error) {
+	body, err := get(ctx, s.client, s.base.JoinPath("api/v1/captions", videoID).String())
+	if err != nil {
+		return nil, fmt.Errorf("list invidious captions: %w", err)
+	}
+
+	var list invidiousCaptions
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("decode invidious captions: %w", err)
+	}
+
+	tracks := make([]youtube.CaptionTrack, 0, len(list.Captions))
+	for _, caption := range list.Captions {
+		ref, err := url.Parse(caption.URL)
+		if err != nil {
+			continue
+		}
+		var kind string
+		if strings.Contains(strings.ToLower(caption.Label), "auto-generated") {
+			kind = "asr"
+		}
+		tracks = append(tracks, youtube.CaptionTrack{
+			Source:       s.Name(),
+			VideoID:      videoID,
+			LanguageCode: caption.LanguageCode,
+			Name:         caption.Label,
+			Kind:         kind,
+			BaseURL:      s.base.ResolveReference(ref).String(),
+		})
+	}
+	return tracks, nil
+}
+
+func (s *invidiousSource) FetchTrack(ctx context.Context, track youtube.CaptionTrack) ([]youtube.TranscriptSegment, error) {
+	body, err := get(ctx, s.client, track.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch invidious caption: %w", err)
+	}
+	segments, err := format.ParseVTT(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse invidious caption: %w", err)
+	}
+	return segments, nil
+}
+
+// VideoDetails returns the title and channel from /api/v1/videos
+func (s *invidiousSource) VideoDetails(ctx context.Context, videoID string) (title, channel string, err error) {
+	u := s.base.JoinPath("api/v1/videos", videoID)
+	u.RawQuery = url.Values{"fields": {"title,author"}}.Encode()
+	body, err := get(ctx, s.client, u.String())
+	if err != nil {
+		return "", "", fmt.Errorf("get invidious video: %w", err)
+	}
+
+	var video struct {
+		Title  string `json:"title"`
+		Author string `json:"author"`
+	}
+	if err := json.Unmarshal(body, &video); err != nil {
+		return "", "", fmt.Errorf("decode invidious video: %w", err)
+	}
+	return video.Title, video.Author, nil
+}
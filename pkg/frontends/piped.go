@@ -0,0 +1,113 @@
+package frontends
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/format"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// pipedSource lists tracks from the subtitles of the Piped /streams
+// endpoint, which also carries the title and uploader
+type pipedSource struct {
+	client *youtube.Client
+	base   *url.URL
+}
+
+// Piped returns a factory for a caption source backed by the Piped API
+// instance at instance, e.g. https://pipedapi.example.org.
+func Piped(instance string) (youtube.SourceFactory, error) {
+	base, err := parseInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *youtube.Client) youtube.CaptionSource {
+		return &pipedSource{client: c, base: base}
+	}, nil
+}
+
+func (s *pipedSource) Name() string {
+	return "piped"
+}
+
+type pipedStreams struct {
+	Title     string `json:"title"`
+	Uploader  string `json:"uploader"`
+	Subtitles []struct {
+		URL           string `json:"url"`
+		Name          string `json:"name"`
+		Code          string `json:"code"`
+		AutoGenerated bool   `json:"autoGenerated"`
+	} `json:"subtitles"`
+}
+
+func (s *pipedSource) streams(ctx context.Context, videoID string) (*pipedStreams, error) {
+	body, err := get(ctx, s.client, s.base.JoinPath("streams", videoID).String())
+	if err != nil {
+		return nil, fmt.Errorf("get piped streams: %w", err)
+	}
+	var streams pipedStreams
+	if err := json.Unmarshal(body, &streams); err != nil {
+		return nil, fmt.Errorf("decode piped streams: %w", err)
+	}
+	return &streams, nil
+}
+
+func (s *pipedSource) ListTracks(ctx context.Context, videoID string, _ youtube.RequestOptions) ([]youtube.CaptionTrack, error) {
+	streams, err := s.streams(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]youtube.CaptionTrack, 0, len(streams.Subtitles))
+	for _, subtitle := range streams.Subtitles {
+		ref, err := url.Parse(subtitle.URL)
+		if err != nil {
+			continue
+		}
+		var kind string
+		if subtitle.AutoGenerated {
+			kind = "asr"
+		}
+		tracks = append(tracks, youtube.CaptionTrack{
+			Source:       s.Name(),
+			VideoID:      videoID,
+			LanguageCode: subtitle.Code,
+			Name:         subtitle.Name,
+			Kind:         kind,
+			BaseURL:      s.base.ResolveReference(ref).String(),
+		})
+	}
+	return tracks, nil
+}
+
+func (s *pipedSource) FetchTrack(ctx context.Context, track youtube.CaptionTrack) ([]youtube.TranscriptSegment, error) {
+	body, err := get(ctx, s.client, track.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch piped subtitle: %w", err)
+	}
+
+	// Subtitles are usually WebVTT, but instances may pass YouTube's TTML on
+	kind := "vtt"
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\ufeff"))), []byte("<")) {
+		kind = "ttml"
+	}
+	segments, err := format.Parse(kind, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse piped subtitle: %w", err)
+	}
+	return segments, nil
+}
+
+// VideoDetails returns the title and uploader from /streams
+func (s *pipedSource) VideoDetails(ctx context.Context, videoID string) (title, channel string, err error) {
+	streams, err := s.streams(ctx, videoID)
+	if err != nil {
+		return "", "", err
+	}
+	return streams.Title, streams.Uploader, nil
+}
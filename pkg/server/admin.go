@@ -0,0 +1,259 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/middleware"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// QueueStatus is served at /api/v1/admin/queue
+type QueueStatus struct {
+	// Upstream lists outbound requests waiting for the rate limiter
+	Upstream []youtube.HostQueue `json:"upstream"`
+	// Requests reports in-flight requests against the concurrency limits
+	Requests *middleware.SlotUsage `json:"requests,omitempty"`
+	// Routes reports in-flight requests against per route limits
+	Routes map[string]middleware.SlotUsage `json:"routes,omitempty"`
+}
+
+// requireAdmin only lets requests through that carry token as a bearer token.
+func requireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			transcript.WriteError(w, r, i18n.Unauthorized, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleQueue reports how saturated the outbound limiter and the concurrency
+// limits are.
+func handleQueue(limiter *youtube.RateLimiter, mw *middleware.Middleware) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, queueStatus(limiter, mw), http.StatusOK)
+	}
+}
+
+func queueStatus(limiter *youtube.RateLimiter, mw *middleware.Middleware) QueueStatus {
+	status := QueueStatus{Upstream: []youtube.HostQueue{}}
+	if limiter != nil {
+		if queues := limiter.Queues(); queues != nil {
+			status.Upstream = queues
+		}
+	}
+	status.Requests, status.Routes = mw.ConcurrencyStats()
+	return status
+}
+
+// UpstreamErrors is served at /api/v1/admin/errors
+type UpstreamErrors struct {
+	Windows []transcript.ErrorRate `json:"windows"`
+}
+
+// handleErrors reports rolling upstream error rates by class, telling
+// YouTube blocking the server apart from users submitting bad URLs.
+func handleErrors(svc *transcript.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, UpstreamErrors{Windows: svc.ErrorRates()}, http.StatusOK)
+	}
+}
+
+// defaultAnalyticsWindow is reported when no window is requested
+const defaultAnalyticsWindow = 24 * time.Hour
+
+// handleAnalytics reports top videos, failure rates and traffic over the
+// window given as a duration such as 24h or 168h.
+func handleAnalytics(svc *transcript.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+
+		window, ok := analyticsWindow(w, r)
+		if !ok {
+			return
+		}
+		report, err := svc.Analytics(window)
+		if errors.Is(err, transcript.ErrNotSupported) {
+			writeAnalyticsDisabled(w, r)
+			return
+		}
+		writeJSON(w, report, http.StatusOK)
+	}
+}
+
+// Dashboard is served at /api/v1/admin/dashboard
+type Dashboard struct {
+	transcript.Dashboard
+	Queue QueueStatus `json:"queue"`
+}
+
+// handleDashboard backs an operator dashboard with traffic, cache efficiency
+// and top errors over the window, aggregated from the analytics log, and the
+// current queue depth.
+func handleDashboard(svc *transcript.Service, limiter *youtube.RateLimiter, mw *middleware.Middleware) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+
+		window, ok := analyticsWindow(w, r)
+		if !ok {
+			return
+		}
+		dashboard, err := svc.Dashboard(window)
+		if errors.Is(err, transcript.ErrNotSupported) {
+			writeAnalyticsDisabled(w, r)
+			return
+		}
+		writeJSON(w, Dashboard{Dashboard: dashboard, Queue: queueStatus(limiter, mw)}, http.StatusOK)
+	}
+}
+
+// analyticsWindow parses the window parameter, responding with an error
+// unless it is a positive duration.
+func analyticsWindow(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	value := r.URL.Query().Get("window")
+	if value == "" {
+		return defaultAnalyticsWindow, true
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		transcript.WriteRequestError(w, r, &transcript.ValidationError{Fields: []transcript.FieldError{
+			transcript.NewFieldError("window", i18n.PositiveDuration),
+		}})
+		return 0, false
+	}
+	return d, true
+}
+
+func writeAnalyticsDisabled(w http.ResponseWriter, r *http.Request) {
+	transcript.WriteError(w, r, i18n.AnalyticsDisabled, http.StatusNotFound)
+}
+
+// handlePurgeVideo deletes everything stored about a video, for deletion
+// requests.
+func handlePurgeVideo(svc *transcript.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+
+		result, err := svc.PurgeVideo(r.Context(), r.PathValue("id"))
+		var validationErr *transcript.ValidationError
+		if errors.As(err, &validationErr) {
+			transcript.WriteRequestError(w, r, err)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to purge video", "video_id", r.PathValue("id"), "error", err)
+			transcript.WriteError(w, r, i18n.InternalError, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, result, http.StatusOK)
+	}
+}
+
+// handlePurgeClient deletes the analytics records of a client IP.
+func handlePurgeClient(svc *transcript.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+
+		result, err := svc.PurgeClient(r.PathValue("ip"))
+		switch {
+		case errors.Is(err, transcript.ErrInvalidClient):
+			transcript.WriteRequestError(w, r, &transcript.ValidationError{Fields: []transcript.FieldError{
+				transcript.NewFieldError("ip", i18n.InvalidIP),
+			}})
+		case err != nil:
+			slog.Error("Failed to purge client", "error", err)
+			transcript.WriteError(w, r, i18n.InternalError, http.StatusInternalServerError)
+		default:
+			writeJSON(w, result, http.StatusOK)
+		}
+	}
+}
+
+// BackfillError is the error response of a backfill that failed part way,
+// with the counts so far
+type BackfillError struct {
+	transcript.ErrorResponse
+	transcript.BackfillResult
+}
+
+// handleBackfill indexes every cached transcript into the search index, for
+// transcripts cached before indexing was enabled.
+func handleBackfill(svc *transcript.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+
+		result, err := svc.Backfill(r.Context())
+		switch {
+		case errors.Is(err, transcript.ErrNotSupported):
+			transcript.WriteError(w, r, i18n.SearchDisabled, http.StatusNotFound)
+		case err != nil:
+			slog.Error("Failed to backfill search index", "error", err)
+			lang := i18n.FromRequest(r)
+			w.Header().Set("Content-Language", lang)
+			writeJSON(w, BackfillError{
+				ErrorResponse: transcript.ErrorResponse{
+					Error:   http.StatusText(http.StatusBadGateway),
+					Message: i18n.T(lang, i18n.BackfillFailed),
+				},
+				BackfillResult: result,
+			}, http.StatusBadGateway)
+		default:
+			writeJSON(w, result, http.StatusOK)
+		}
+	}
+}
+
+// handleMetrics serves the service counters in the Prometheus text format.
+func handleMetrics(svc *transcript.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := svc.WritePrometheus(w); err != nil {
+			slog.Error("Failed to write metrics", "error", err)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, body any, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// Meta describes the deployment at /api/v1/meta so that clients and the UI
+// can adapt to its capabilities
+type Meta struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit"`
+	BuildDate string   `json:"buildDate"`
+	Features  Features `json:"features"`
+	// CaptionSources are tried in order when fetching a transcript
+	CaptionSources []string `json:"captionSources"`
+	Limits         Limits   `json:"limits"`
+}
+
+// Features lists which optional parts of the API are enabled
+type Features struct {
+	// SearchIndex is set when transcripts are indexed into a search cluster
+	SearchIndex   bool `json:"searchIndex"`
+	UI            bool `json:"ui"`
+	MCP           bool `json:"mcp"`
+	ToolsAPI      bool `json:"toolsApi"`
+	OAuth         bool `json:"oauth"`
+	PopularVideos bool `json:"popularVideos"`
+	// ProofOfWork is set when clients must solve a challenge first
+	ProofOfWork bool `json:"proofOfWork"`
+}
+
+// Limits are the bounds enforced on requests
+type Limits struct {
+	DefaultIntervalSeconds float64 `json:"defaultIntervalSeconds"`
+	MinIntervalSeconds     float64 `json:"minIntervalSeconds"`
+	MaxIntervalSeconds     float64 `json:"maxIntervalSeconds"`
+	MaxLanguages           int     `json:"maxLanguages"`
+	MinTokenBudget         int     `json:"minTokenBudget"`
+	MaxTokenBudget         int     `json:"maxTokenBudget"`
+	MaxUploadBytes         int64   `json:"maxUploadBytes"`
+	// MaxConcurrentRequests is zero when unlimited
+	MaxConcurrentRequests int `json:"maxConcurrentRequests"`
+}
+
+// newMeta describes the deployment configured by cfg
+func newMeta(cfg Config, fetcher transcript.TranscriptFetcher) Meta {
+	sources := []string{}
+	if client, ok := fetcher.(*youtube.Client); ok {
+		sources = client.SourceNames()
+	}
+	return Meta{
+		Version:   cfg.Version,
+		Commit:    cfg.Commit,
+		BuildDate: cfg.BuildDate,
+		Features: Features{
+			SearchIndex:   cfg.SearchURL != "",
+			UI:            cfg.UI != nil,
+			MCP:           cfg.MCP,
+			ToolsAPI:      cfg.ToolsAPI,
+			OAuth:         cfg.OAuth != nil,
+			PopularVideos: cfg.PublicPopular && cfg.AnalyticsFile != "",
+			ProofOfWork:   cfg.ProofOfWorkDifficulty > 0,
+		},
+		CaptionSources: sources,
+		Limits: Limits{
+			DefaultIntervalSeconds: transcript.DefaultIntervalSeconds,
+			MinIntervalSeconds:     transcript.MinIntervalSeconds,
+			MaxIntervalSeconds:     transcript.MaxIntervalSeconds,
+			MaxLanguages:           transcript.MaxLanguages,
+			MinTokenBudget:         transcript.MinTokenBudget,
+			MaxTokenBudget:         transcript.MaxTokenBudget,
+			MaxUploadBytes:         transcript.MaxUploadBytes,
+			MaxConcurrentRequests:  cfg.MaxConcurrentRequests,
+		},
+	}
+}
+
+// handleMeta serves meta.
+func handleMeta(meta Meta) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, meta, http.StatusOK)
+	}
+}
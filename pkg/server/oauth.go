@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/i18n"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// oauthStateTTL bounds how long an authorization may take
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthStatus is served at /api/v1/admin/oauth
+type OAuthStatus struct {
+	Authorized bool `json:"authorized"`
+	// AuthorizeURL is the Google consent page the account owner opens to
+	// authorize access to their videos
+	AuthorizeURL string `json:"authorizeUrl"`
+}
+
+// oauthFlow hands out authorization URLs to admins and completes them at
+// the public callback, matching both by the state parameter
+type oauthFlow struct {
+	oauth *youtube.OAuth
+
+	mu     sync.Mutex
+	states map[string]time.Time
+}
+
+func newOAuthFlow(oauth *youtube.OAuth) *oauthFlow {
+	return &oauthFlow{oauth: oauth, states: make(map[string]time.Time)}
+}
+
+// handleStatus reports whether an account is authorized along with a fresh
+// authorization URL.
+func (f *oauthFlow) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		slog.Error("Failed to generate oauth state", "error", err)
+		transcript.WriteError(w, r, i18n.InternalError, http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(buf)
+
+	f.mu.Lock()
+	now := time.Now()
+	for s, expiry := range f.states {
+		if now.After(expiry) {
+			delete(f.states, s)
+		}
+	}
+	f.states[state] = now.Add(oauthStateTTL)
+	f.mu.Unlock()
+
+	writeJSON(w, OAuthStatus{
+		Authorized:   f.oauth.Authorized(),
+		AuthorizeURL: f.oauth.AuthCodeURL(state),
+	}, http.StatusOK)
+}
+
+// handleCallback is the OAuth redirect URL. It exchanges the code for a token
+// if the state was handed out by handleStatus.
+func (f *oauthFlow) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		transcript.WriteError(w, r, i18n.MethodNotAllowed, http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	state := q.Get("state")
+	f.mu.Lock()
+	expiry, ok := f.states[state]
+	delete(f.states, state)
+	f.mu.Unlock()
+	if !ok || time.Now().After(expiry) {
+		transcript.WriteError(w, r, i18n.OAuthStateExpired, http.StatusBadRequest, "/api/v1/admin/oauth")
+		return
+	}
+	if reason := q.Get("error"); reason != "" {
+		transcript.WriteError(w, r, i18n.OAuthDenied, http.StatusBadRequest, reason)
+		return
+	}
+
+	if err := f.oauth.Exchange(r.Context(), q.Get("code")); err != nil {
+		slog.Error("Failed to exchange oauth code", "error", err)
+		transcript.WriteError(w, r, i18n.OAuthExchangeFailed, http.StatusBadGateway)
+		return
+	}
+	slog.Info("YouTube account authorized")
+	writeJSON(w, map[string]bool{"authorized": true}, http.StatusOK)
+}
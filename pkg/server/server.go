@@ -0,0 +1,392 @@
+// Package server assembles the transcript API into an embeddable HTTP server
+// so that other Go applications can mount it under their own router instead
+// of running the binary.
+package server
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/flags"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/middleware"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/tools"
+	"github.com/ahmethakanbesel/youtube-video-summary/internal/transcript"
+	"github.com/ahmethakanbesel/youtube-video-summary/pkg/youtube"
+)
+
+// DefaultCORSMaxAge is the longest preflight cache Chromium honors
+const DefaultCORSMaxAge = 2 * time.Hour
+
+// DefaultProofOfWorkRoutes are the expensive routes guarded by proof of work
+// when it is enabled without explicit routes
+var DefaultProofOfWorkRoutes = []string{
+	"/api/v1/transcripts",
+	"/api/v1/transcripts/upload",
+	"/api/v1/videos/{id}/html",
+	"/api/v1/videos/{id}/tokens",
+	"/api/v1/videos/{id}/highlights",
+	"/api/v1/videos/{id}/captions/{lang}/raw",
+	"/api/v1/ext/summary",
+	"/api/v1/mcp",
+	"/api/v1/tools/call",
+}
+
+// Config configures a Server. The zero value is usable.
+type Config struct {
+	// Addr is the listen address used by Run, ":8080" by default
+	Addr string
+	// BasePath mounts the API and UI under a path prefix such as "/yt", for
+	// deployments behind a path-prefixing reverse proxy
+	BasePath string
+	// YouTubeAPIKey is an optional InnerTube API key
+	YouTubeAPIKey string
+	// InsecureSkipVerify disables TLS verification of upstream requests
+	InsecureSkipVerify bool
+	// ClientOptions are passed to youtube.NewClient
+	ClientOptions []youtube.Option
+	// OAuth, when set, downloads captions through the Data API as the
+	// account authorized at /api/v1/admin/oauth, covering its private and
+	// unlisted videos. It requires AdminToken.
+	OAuth *youtube.OAuth
+	// Fetcher replaces the YouTube client as the transcript source when set
+	Fetcher transcript.TranscriptFetcher
+	// ShadowClientOptions, when not nil, enables shadow mode: a sample of
+	// upstream fetches is repeated with a client built from ClientOptions
+	// followed by these options, and the results are compared in the logs
+	ShadowClientOptions []youtube.Option
+	// ShadowSampleRate is the fraction of fetches repeated in shadow mode
+	ShadowSampleRate float64
+	// CacheTTL expires fetched transcripts, zero keeping them until restart
+	CacheTTL time.Duration
+	// StaleWhileRevalidate serves expired transcripts immediately while they
+	// are refreshed in the background
+	StaleWhileRevalidate bool
+	// CompressCache stores cached transcripts gzip compressed
+	CompressCache bool
+	// CacheEncryptionKey seals cached transcripts with AES-GCM when set. It
+	// must be 16, 24 or 32 bytes long.
+	CacheEncryptionKey []byte
+	// MaxCacheBytes bounds the approximate memory used by cached
+	// transcripts, zero for unlimited
+	MaxCacheBytes int64
+	// YtDlpArchiveDir, when set, is imported in the background by Run: every
+	// yt-dlp info.json in it is ingested along with its subtitle files
+	YtDlpArchiveDir string
+	// WatchDir, when set, is a drop folder watched by Run: subtitle files
+	// named by video ID, yt-dlp downloads and URL lists put there are
+	// processed automatically
+	WatchDir string
+	// WatchInterval is how often WatchDir is scanned, 10s by default
+	WatchInterval time.Duration
+	// TimestampStyle lays out the timestamps of formatted transcripts, see
+	// format.TimestampStyle. TimestampStyles overrides it per language code.
+	TimestampStyle  string
+	TimestampStyles map[string]string
+	// FeatureFlagsFile, when set, is a JSON file of feature names and
+	// booleans switching experimental behavior, reloaded when it changes
+	FeatureFlagsFile string
+	// SearchURL, when set, is an Elasticsearch or OpenSearch cluster every
+	// fetched or ingested transcript is indexed into, with segments as nested
+	// documents. Basic auth credentials may be part of the URL.
+	SearchURL string
+	// SearchIndex is the index name, "transcripts" by default
+	SearchIndex string
+	// SearchAPIKey authenticates to the cluster instead of basic auth
+	SearchAPIKey string
+	// VaultDir, when set, receives a Markdown note with YAML front matter for
+	// every fetched or uploaded transcript, e.g. an Obsidian vault
+	VaultDir string
+	// VaultTags are added to the front matter of vault notes
+	VaultTags []string
+	// TrustedProxies are IPs or CIDRs whose X-Forwarded-For and X-Real-IP
+	// headers are believed
+	TrustedProxies []string
+	// AllowedIPs, when not empty, are the only client IPs or CIDRs served
+	AllowedIPs []string
+	// DeniedIPs are client IPs or CIDRs rejected with 403
+	DeniedIPs []string
+	// ProofOfWorkDifficulty is the number of leading zero bits a solved
+	// challenge must have on ProofOfWorkRoutes. Zero disables the check.
+	ProofOfWorkDifficulty int
+	// ProofOfWorkSecret signs challenges, random per process when empty
+	ProofOfWorkSecret string
+	// ProofOfWorkRoutes are the route patterns requiring a solved challenge,
+	// in the syntax of http.ServeMux, DefaultProofOfWorkRoutes when empty
+	ProofOfWorkRoutes []string
+	// AnalyticsFile, when set, is a JSON lines log every transcript request is
+	// appended to and that is replayed on startup for /api/v1/admin/analytics
+	AnalyticsFile string
+	// PublicPopular publishes the most requested videos from the analytics
+	// log at /api/v1/stats/popular
+	PublicPopular bool
+	// MCP serves the transcript tools to LLM agents over the Model Context
+	// Protocol at /api/v1/mcp
+	MCP bool
+	// ToolsAPI serves the same tools in the OpenAI function calling format at
+	// /api/v1/tools/schema and runs calls posted to /api/v1/tools/call
+	ToolsAPI bool
+	// Version, Commit and BuildDate identify the build at /api/v1/meta and
+	// to MCP clients
+	Version   string
+	Commit    string
+	BuildDate string
+	// CORSOrigins are allowed cross-origin requests, e.g. the
+	// chrome-extension:// origin of a browser extension
+	CORSOrigins []string
+	// CORSMaxAge is how long browsers cache preflight responses
+	CORSMaxAge time.Duration
+	// AdminToken enables the /api/v1/admin endpoints for requests carrying it
+	// as a bearer token. Empty disables them.
+	AdminToken string
+	// UI holds the built web UI served at "/". Nil serves the API only.
+	UI fs.FS
+	// MaxConcurrentRequests bounds in-flight requests, zero for unlimited
+	MaxConcurrentRequests int
+	// RouteConcurrencyLimits bounds in-flight requests per route pattern,
+	// such as /api/v1/videos/{id}/html or the prefix /api/v1/videos/
+	RouteConcurrencyLimits map[string]int
+	// ShutdownTimeout bounds graceful shutdown in Run, 5s by default
+	ShutdownTimeout time.Duration
+	// Logger defaults to a text logger on stdout
+	Logger *slog.Logger
+}
+
+// Server is the transcript API with its middleware applied
+type Server struct {
+	cfg     Config
+	logger  *slog.Logger
+	handler http.Handler
+	service *transcript.Service
+	flags   *flags.Flags
+}
+
+// New wires up the YouTube client, repository, service and router.
+func New(cfg Config) (*Server, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 5 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	}
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		return nil, err
+	}
+	cfg.BasePath = normalizeBasePath(cfg.BasePath)
+
+	if cfg.OAuth != nil {
+		if cfg.AdminToken == "" {
+			return nil, errors.New("oauth requires an admin token to authorize an account")
+		}
+		cfg.ClientOptions = append(slices.Clone(cfg.ClientOptions), youtube.WithOAuth(cfg.OAuth))
+	}
+
+	var featureFlags *flags.Flags
+	if cfg.FeatureFlagsFile != "" {
+		var err error
+		if featureFlags, err = flags.Load(cfg.FeatureFlagsFile, cfg.Logger); err != nil {
+			return nil, err
+		}
+		cfg.ClientOptions = append(slices.Clone(cfg.ClientOptions), youtube.WithFeatures(featureFlags))
+	}
+
+	fetcher := cfg.Fetcher
+	if fetcher == nil {
+		fetcher = youtube.NewClient(cfg.YouTubeAPIKey, cfg.InsecureSkipVerify, cfg.Logger, cfg.ClientOptions...)
+	}
+	repo := transcript.NewMemoryRepository(cfg.Logger)
+	repo.SetTTL(cfg.CacheTTL, cfg.StaleWhileRevalidate)
+	repo.SetCompression(cfg.CompressCache)
+	repo.SetMaxBytes(cfg.MaxCacheBytes)
+	if cfg.CacheEncryptionKey != nil {
+		if err := repo.SetEncryptionKey(cfg.CacheEncryptionKey); err != nil {
+			return nil, err
+		}
+	}
+	svc := transcript.NewService(fetcher, repo, cfg.Logger)
+	if featureFlags != nil {
+		svc.SetFeatures(featureFlags)
+	}
+	if cfg.ShadowClientOptions != nil {
+		shadowOpts := append(slices.Clone(cfg.ClientOptions), cfg.ShadowClientOptions...)
+		shadowClient := youtube.NewClient(cfg.YouTubeAPIKey, cfg.InsecureSkipVerify, cfg.Logger, shadowOpts...)
+		svc.SetShadow(shadowClient, cfg.ShadowSampleRate)
+	}
+	if err := svc.SetTimestampStyles(cfg.TimestampStyle, cfg.TimestampStyles); err != nil {
+		return nil, err
+	}
+	if err := svc.SetVault(cfg.VaultDir, cfg.VaultTags); err != nil {
+		return nil, err
+	}
+	if err := svc.SetSearchIndex(cfg.SearchURL, cfg.SearchIndex, cfg.SearchAPIKey); err != nil {
+		return nil, err
+	}
+	if err := svc.SetAnalytics(cfg.AnalyticsFile); err != nil {
+		return nil, err
+	}
+	svc.SetPopularVisible(cfg.PublicPopular)
+	svc.SetUploadToken(cfg.AdminToken)
+	rtr := transcript.NewRouter(svc, cfg.UI)
+
+	mw := middleware.NewMiddleware(cfg.Logger)
+	if err := mw.SetConcurrencyLimits(cfg.MaxConcurrentRequests, cfg.RouteConcurrencyLimits); err != nil {
+		return nil, err
+	}
+	if err := mw.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	if err := mw.SetIPFilter(cfg.AllowedIPs, cfg.DeniedIPs); err != nil {
+		return nil, err
+	}
+	if err := mw.SetCORS(cfg.CORSOrigins, cfg.CORSMaxAge); err != nil {
+		return nil, err
+	}
+	powRoutes := cfg.ProofOfWorkRoutes
+	if len(powRoutes) == 0 {
+		powRoutes = DefaultProofOfWorkRoutes
+	}
+	if err := mw.SetProofOfWork(cfg.ProofOfWorkDifficulty, cfg.ProofOfWorkSecret, powRoutes); err != nil {
+		return nil, err
+	}
+
+	rtr.HandleFunc("/api/v1/meta", handleMeta(newMeta(cfg, fetcher)))
+	if cfg.MCP {
+		rtr.Handle("/api/v1/mcp", tools.NewMCPHandler(svc, cfg.Version, cfg.Logger))
+	}
+	if cfg.ToolsAPI {
+		functions := tools.NewFunctionHandlers(svc, cfg.Logger)
+		rtr.HandleFunc("/api/v1/tools/schema", functions.HandleSchema)
+		rtr.HandleFunc("/api/v1/tools/call", functions.HandleCall)
+	}
+
+	if cfg.AdminToken != "" {
+		limiter := youtube.SharedRateLimiter
+		if client, ok := fetcher.(*youtube.Client); ok {
+			limiter = client.RateLimiter()
+		}
+		rtr.HandleFunc("/api/v1/admin/queue", requireAdmin(cfg.AdminToken, handleQueue(limiter, mw)))
+		rtr.HandleFunc("/api/v1/admin/errors", requireAdmin(cfg.AdminToken, handleErrors(svc)))
+		rtr.HandleFunc("/api/v1/admin/analytics", requireAdmin(cfg.AdminToken, handleAnalytics(svc)))
+		rtr.HandleFunc("/api/v1/admin/dashboard", requireAdmin(cfg.AdminToken, handleDashboard(svc, limiter, mw)))
+		rtr.HandleFunc("/api/v1/admin/videos/{id}", requireAdmin(cfg.AdminToken, handlePurgeVideo(svc)))
+		rtr.HandleFunc("/api/v1/admin/clients/{ip}", requireAdmin(cfg.AdminToken, handlePurgeClient(svc)))
+		rtr.HandleFunc("/api/v1/admin/search/backfill", requireAdmin(cfg.AdminToken, handleBackfill(svc)))
+		rtr.HandleFunc("/metrics", requireAdmin(cfg.AdminToken, handleMetrics(svc)))
+	}
+	if cfg.OAuth != nil {
+		flow := newOAuthFlow(cfg.OAuth)
+		rtr.HandleFunc("/api/v1/admin/oauth", requireAdmin(cfg.AdminToken, flow.handleStatus))
+		rtr.HandleFunc("/api/v1/oauth/callback", flow.handleCallback)
+	}
+
+	return &Server{
+		cfg:     cfg,
+		logger:  cfg.Logger,
+		handler: withBasePath(cfg.BasePath, mw.Apply(rtr)),
+		service: svc,
+		flags:   featureFlags,
+	}, nil
+}
+
+// normalizeBasePath returns "" or a path with a leading and no trailing slash
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// withBasePath serves next under basePath, redirecting the bare prefix to its
+// trailing slash form so that relative UI links resolve.
+func withBasePath(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(basePath+"/", http.StripPrefix(basePath, next))
+	mux.Handle(basePath, http.RedirectHandler(basePath+"/", http.StatusMovedPermanently))
+	return mux
+}
+
+// Handler returns the API and UI handler with all middleware applied
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Service returns the underlying transcript service
+func (s *Server) Service() *transcript.Service {
+	return s.service
+}
+
+// Run listens on the configured address until ctx is cancelled and then shuts
+// the server down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.cfg.Addr,
+		Handler: s.handler,
+	}
+
+	if dir := s.cfg.YtDlpArchiveDir; dir != "" {
+		go s.importArchive(ctx, dir)
+	}
+	if s.flags != nil {
+		go s.flags.Watch(ctx, flags.DefaultReloadInterval)
+	}
+	if dir := s.cfg.WatchDir; dir != "" {
+		go func() {
+			if err := s.service.Watch(ctx, dir, s.cfg.WatchInterval); err != nil {
+				s.logger.Error("Failed to watch folder", "dir", dir, "error", err)
+			}
+		}()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info("Starting server", "addr", srv.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	s.logger.Info("Server stopped")
+	return nil
+}
+
+// importArchive ingests a yt-dlp download directory, logging the outcome.
+func (s *Server) importArchive(ctx context.Context, dir string) {
+	result, err := s.service.ImportYtDlpDir(ctx, dir)
+	for file, reason := range result.Failed {
+		s.logger.Warn("Failed to import yt-dlp video", "file", file, "error", reason)
+	}
+	if err != nil {
+		s.logger.Error("Failed to import yt-dlp archive", "dir", dir, "error", err)
+		return
+	}
+	s.logger.Info("Imported yt-dlp archive", "dir", dir, "videos", len(result.Imported), "failed", len(result.Failed))
+}
@@ -0,0 +1,100 @@
+package youtube
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPlayerCacheTTL  = 5 * time.Minute
+	defaultPlayerCacheSize = 256
+	defaultRawCacheTTL     = 10 * time.Minute
+	defaultRawCacheSize    = 64
+)
+
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// lruCache is a small LRU with a per-entry TTL.
+type lruCache[V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// playerCache holds parsed player responses. It lets caption listing and
+// transcript fetches for the same video share a single InnerTube call.
+type playerCache = lruCache[*playerResponse]
+
+func newPlayerCache(ttl time.Duration, size int) *playerCache {
+	return newLRUCache[*playerResponse](ttl, size)
+}
+
+func newLRUCache[V any](ttl time.Duration, size int) *lruCache[V] {
+	return &lruCache[V]{
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache[V]) get(key string) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := el.Value.(*lruEntry[V])
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return zero, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *lruCache[V]) put(key string, value V) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
@@ -0,0 +1,86 @@
+package youtube
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// minChapters is the number of timestamps YouTube requires in a description
+// before it shows them as chapters
+const minChapters = 3
+
+// Chapter is a section of a video defined by its creator
+type Chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+}
+
+var (
+	// "0:00 Intro", "[01:02:03] - Part two"
+	chapterLeading = regexp.MustCompile(`^[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?\s*(?:[-–—:|•]\s*)?(.+)$`)
+	// "Intro - 0:00"
+	chapterTrailing = regexp.MustCompile(`^(.+?)\s*(?:[-–—:|•]\s*)?[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?$`)
+)
+
+// ParseChapters reads chapters from a video description the way YouTube does:
+// one line per chapter with a timestamp before or after the title, the first
+// at 0:00, at least three of them and in ascending order. Descriptions that
+// do not qualify yield nil.
+func ParseChapters(description string) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		var stamp, title string
+		if m := chapterLeading.FindStringSubmatch(line); m != nil {
+			stamp, title = m[1], m[2]
+		} else if m := chapterTrailing.FindStringSubmatch(line); m != nil {
+			title, stamp = m[1], m[2]
+		} else {
+			continue
+		}
+
+		start, ok := parseClock(stamp)
+		if !ok {
+			continue
+		}
+		if len(chapters) == 0 && start != 0 {
+			continue
+		}
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			return nil
+		}
+		chapters = append(chapters, Chapter{Title: strings.TrimSpace(title), Start: start})
+	}
+	if len(chapters) < minChapters {
+		return nil
+	}
+	return chapters
+}
+
+// parseClock converts "m:ss" or "h:mm:ss" to seconds
+func parseClock(stamp string) (float64, bool) {
+	seconds := 0
+	parts := strings.Split(stamp, ":")
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || (i > 0 && n > 59) {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return float64(seconds), true
+}
+
+// videoChapters returns the chapters in the description of the player
+// response, nil when it has none or cannot be fetched. The response is
+// usually cached from listing tracks already.
+func (c *Client) videoChapters(ctx context.Context, videoID string, opts RequestOptions) []Chapter {
+	playerResp, err := c.getPlayerResponse(ctx, videoID, opts)
+	if err != nil {
+		c.logger.Debug("Failed to get player response for chapters", "video_id", videoID, "error", err)
+		return nil
+	}
+	return ParseChapters(playerResp.VideoDetails.ShortDescription)
+}
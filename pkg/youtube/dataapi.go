@@ -0,0 +1,162 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const dataAPIEndpoint = "https://www.googleapis.com/youtube/v3"
+
+// dataAPISource lists and downloads tracks with the official Data API
+// captions.list and captions.download methods. Both need an authorized
+// account, and downloads only succeed for videos it may edit.
+type dataAPISource struct {
+	client *Client
+}
+
+func (s *dataAPISource) Name() string {
+	return "dataapi"
+}
+
+type captionListResponse struct {
+	Items []struct {
+		ID      string `json:"id"`
+		Snippet struct {
+			Language  string `json:"language"`
+			Name      string `json:"name"`
+			TrackKind string `json:"trackKind"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+func (s *dataAPISource) ListTracks(ctx context.Context, videoID string, _ RequestOptions) ([]CaptionTrack, error) {
+	q := url.Values{}
+	q.Set("part", "snippet")
+	q.Set("videoId", videoID)
+
+	body, err := s.client.getDataAPI(ctx, dataAPIEndpoint+"/captions?"+q.Encode())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list data api captions")
+	}
+
+	var list captionListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode data api caption list")
+	}
+
+	tracks := make([]CaptionTrack, 0, len(list.Items))
+	for _, item := range list.Items {
+		var kind string
+		if strings.EqualFold(item.Snippet.TrackKind, "asr") {
+			kind = "asr"
+		}
+		tracks = append(tracks, CaptionTrack{
+			Source:       s.Name(),
+			VideoID:      videoID,
+			LanguageCode: item.Snippet.Language,
+			Name:         item.Snippet.Name,
+			Kind:         kind,
+			BaseURL:      dataAPIEndpoint + "/captions/" + url.PathEscape(item.ID),
+		})
+	}
+	return tracks, nil
+}
+
+func (s *dataAPISource) FetchTrack(ctx context.Context, track CaptionTrack) ([]TranscriptSegment, error) {
+	body, err := s.FetchRaw(ctx, track, "ttml")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download data api caption")
+	}
+
+	segments, err := parseTTMLTranscript(bytes.NewReader(body), bytes.Count(body, []byte("<p ")), s.client.parseOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse TTML transcript")
+	}
+	return segments, nil
+}
+
+// FetchRaw downloads the track as TTML, the only format of GetRawCaptions
+// that captions.download offers.
+func (s *dataAPISource) FetchRaw(ctx context.Context, track CaptionTrack, format string) ([]byte, error) {
+	if format != "ttml" {
+		return nil, errors.Wrapf(ErrUnsupportedFormat, "format %q", format)
+	}
+	return s.client.getDataAPI(ctx, track.BaseURL+"?tfmt=ttml")
+}
+
+// sourceAllowed reports whether source may serve a request with opts. Unless
+// the client was created WithDataAPI, the dataapi source is a fallback that
+// only serves requests WithAccount, as it sees the authorized account's
+// private videos and spends Data API quota.
+func (c *Client) sourceAllowed(source CaptionSource, opts RequestOptions) bool {
+	_, private := source.(*dataAPISource)
+	return !private || c.dataAPI || opts.Account
+}
+
+type videoListResponse struct {
+	Items []struct {
+		Snippet struct {
+			Title        string `json:"title"`
+			ChannelTitle string `json:"channelTitle"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+// WithDataAPI restricts the client to the official YouTube Data API for
+// operators who must not scrape: captions come from the dataapi source only
+// and titles from videos.list, so no InnerTube or timedtext request is made.
+// Caption requests need WithOAuth, titles use the authorized account or the
+// client's API key as a Data API key. Every video costs around 250 units of
+// the daily quota, mostly for captions.download.
+func WithDataAPI() Option {
+	return func(c *Client) {
+		c.dataAPI = true
+	}
+}
+
+// dataAPIVideoDetails returns the title and channel name from videos.list,
+// or empty strings when they cannot be determined.
+func (c *Client) dataAPIVideoDetails(ctx context.Context, videoID string) (title, channel string) {
+	q := url.Values{}
+	q.Set("part", "snippet")
+	q.Set("id", videoID)
+
+	var (
+		body []byte
+		err  error
+	)
+	if c.oauth != nil && c.oauth.Authorized() {
+		body, err = c.getDataAPI(ctx, dataAPIEndpoint+"/videos?"+q.Encode())
+	} else {
+		body, err = c.getBodyWithHeader(ctx, dataAPIEndpoint+"/videos?"+q.Encode(), http.Header{apiKeyHeader: {c.apiKey}})
+	}
+	if err != nil {
+		c.logger.Warn("Failed to list video for title", "video_id", videoID, "error", err)
+		return "", ""
+	}
+
+	var list videoListResponse
+	if err := json.Unmarshal(body, &list); err != nil || len(list.Items) == 0 {
+		c.logger.Warn("No video found in data api response", "video_id", videoID)
+		return "", ""
+	}
+	return list.Items[0].Snippet.Title, list.Items[0].Snippet.ChannelTitle
+}
+
+// getDataAPI performs a Data API GET request as the authorized account.
+func (c *Client) getDataAPI(ctx context.Context, rawURL string) ([]byte, error) {
+	if c.oauth == nil {
+		return nil, ErrNotAuthorized
+	}
+	token, err := c.oauth.AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.getBodyWithHeader(ctx, rawURL, http.Header{"Authorization": {"Bearer " + token}})
+}
@@ -0,0 +1,107 @@
+package youtube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoCaptions is returned when no source lists any caption track
+var ErrNoCaptions = errors.New("no caption tracks available")
+
+// StatusError is returned when YouTube answers with an unexpected status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Error classes returned by ClassifyError
+const (
+	ErrorClassDNS        = "dns"
+	ErrorClassTLS        = "tls"
+	ErrorClassTimeout    = "timeout"
+	ErrorClassConnection = "connection"
+	ErrorClassForbidden  = "http_403"
+	ErrorClassThrottled  = "http_429"
+	ErrorClassServer     = "http_5xx"
+	ErrorClassHTTP       = "http_other"
+	ErrorClassParse      = "parse"
+	ErrorClassNoCaptions = "no_captions"
+	ErrorClassCanceled   = "canceled"
+	ErrorClassOther      = "other"
+)
+
+// ErrorClasses lists every class ClassifyError may return
+func ErrorClasses() []string {
+	return []string{
+		ErrorClassDNS, ErrorClassTLS, ErrorClassTimeout, ErrorClassConnection,
+		ErrorClassForbidden, ErrorClassThrottled, ErrorClassServer, ErrorClassHTTP,
+		ErrorClassParse, ErrorClassNoCaptions, ErrorClassCanceled, ErrorClassOther,
+	}
+}
+
+// ClassifyError tells apart why a request to YouTube failed, so that being
+// blocked (http_403, http_429) can be told from network trouble or videos
+// without captions. It returns "" for a nil error.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var (
+		statusErr    *StatusError
+		dnsErr       *net.DNSError
+		netErr       net.Error
+		certErr      *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		jsonErr      *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		xmlErr       *xml.SyntaxError
+		opErr        *net.OpError
+	)
+	switch {
+	case errors.Is(err, ErrNoCaptions), errors.Is(err, ErrTrackNotFound):
+		return ErrorClassNoCaptions
+	case errors.As(err, &statusErr):
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusForbidden:
+			return ErrorClassForbidden
+		case code == http.StatusTooManyRequests:
+			return ErrorClassThrottled
+		case code >= 500:
+			return ErrorClassServer
+		default:
+			return ErrorClassHTTP
+		}
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return ErrorClassTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.As(err, &jsonErr), errors.As(err, &typeErr), errors.As(err, &xmlErr),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassParse
+	case errors.As(err, &opErr):
+		return ErrorClassConnection
+	default:
+		return ErrorClassOther
+	}
+}
@@ -0,0 +1,28 @@
+package youtube
+
+// FeatureJSON3 downloads automatic InnerTube tracks as json3, which carries
+// per word confidence, instead of TTML even when no confidence is requested.
+// It is off by default.
+const FeatureJSON3 = "json3"
+
+// Features switches client behavior at runtime
+type Features interface {
+	// Enabled reports whether the feature name is on, def when unset
+	Enabled(name string, def bool) bool
+}
+
+// WithFeatures checks features before each request that depends on one,
+// so that switching a feature takes effect without a restart.
+func WithFeatures(features Features) Option {
+	return func(c *Client) {
+		c.features = features
+	}
+}
+
+// feature reports whether name is enabled, def without Features.
+func (c *Client) feature(name string, def bool) bool {
+	if c.features == nil {
+		return def
+	}
+	return c.features.Enabled(name, def)
+}
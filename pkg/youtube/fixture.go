@@ -0,0 +1,215 @@
+package youtube
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sensitiveParams are stripped from recorded URLs, of requests as well as in
+// response bodies: API keys, the client IP and the signature, expiry and PO
+// token of caption URLs, all bound to the recording session
+var sensitiveParams = []string{"key", "ip", "ipbits", "ei", "expire", "sparams", "signature", "pot", "potc"}
+
+// sensitiveFields are redacted in recorded JSON response bodies
+var sensitiveFields = []string{"visitorData"}
+
+// fixture is a recorded upstream exchange
+type fixture struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+}
+
+// WithRecording saves every upstream response to dir as a sanitized JSON
+// fixture that WithReplay can serve back.
+func WithRecording(dir string) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = &recordingTransport{base: c.httpClient.Transport, dir: dir, client: c}
+	}
+}
+
+// WithReplay serves upstream responses from fixtures recorded to dir instead of
+// contacting YouTube, for offline development and deterministic tests.
+func WithReplay(dir string) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = &replayTransport{dir: dir}
+	}
+}
+
+type recordingTransport struct {
+	base   http.RoundTripper
+	dir    string
+	client *Client
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response for recording")
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := fixture{
+		Method:      req.Method,
+		URL:         sanitizeURL(req.URL),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(sanitizeBody(resp.Header.Get("Content-Type"), body)),
+	}
+	if err := writeFixture(t.dir, fixtureName(req.Method, req.URL, reqBody), rec); err != nil {
+		t.client.logger.Warn("Failed to record fixture", "url", rec.URL, "error", err)
+	}
+
+	return resp, nil
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fixtureName(req.Method, req.URL, reqBody)
+	data, err := os.ReadFile(filepath.Join(t.dir, name))
+	if err != nil {
+		return nil, errors.Wrapf(err, "no fixture for %s %s", req.Method, sanitizeURL(req.URL))
+	}
+
+	var rec fixture
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, errors.Wrapf(err, "invalid fixture %s", name)
+	}
+
+	header := make(http.Header)
+	if rec.ContentType != "" {
+		header.Set("Content-Type", rec.ContentType)
+	}
+	return &http.Response{
+		Status:        http.StatusText(rec.Status),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(rec.Body))),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody returns the request body and restores it for sending
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read request body")
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// fixtureName derives a stable file name from the sanitized request
+func fixtureName(method string, u *url.URL, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+sanitizeURL(u)+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:32] + ".json"
+}
+
+func sanitizeURL(u *url.URL) string {
+	clean := *u
+	q := clean.Query()
+	for _, param := range sensitiveParams {
+		q.Del(param)
+	}
+	clean.RawQuery = q.Encode()
+	return clean.String()
+}
+
+// sanitizeBody strips sensitiveParams from the URLs in a JSON body, such as
+// the caption baseUrls of a player response, and redacts sensitiveFields.
+// Other bodies are returned unchanged. Replayed URLs are sanitized the same
+// way, so they still match their fixtures.
+func sanitizeBody(contentType string, body []byte) []byte {
+	if !strings.Contains(contentType, "json") {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+	clean, err := json.Marshal(sanitizeJSON(doc))
+	if err != nil {
+		return body
+	}
+	return clean
+}
+
+func sanitizeJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if _, ok := value.(string); ok && slices.Contains(sensitiveFields, key) {
+				v[key] = "REDACTED"
+				continue
+			}
+			v[key] = sanitizeJSON(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = sanitizeJSON(value)
+		}
+	case string:
+		if !strings.HasPrefix(v, "https://") && !strings.HasPrefix(v, "http://") {
+			return v
+		}
+		if u, err := url.Parse(v); err == nil && u.RawQuery != "" {
+			return sanitizeURL(u)
+		}
+	}
+	return v
+}
+
+func writeFixture(dir, name string, rec fixture) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
@@ -0,0 +1,72 @@
+package youtube
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxASRConfidence is the top of the acAsrConf scale used by json3 tracks
+const maxASRConfidence = 255
+
+// json3Transcript is YouTube's fmt=json3 caption document
+type json3Transcript struct {
+	Events []struct {
+		StartMs    int64 `json:"tStartMs"`
+		DurationMs int64 `json:"dDurationMs"`
+		Segs       []struct {
+			Text string `json:"utf8"`
+			// Conf is the speech recognition confidence of the word, 0-255.
+			// It is only present on automatic tracks.
+			Conf *int `json:"acAsrConf"`
+		} `json:"segs"`
+	} `json:"events"`
+}
+
+// ParseJSON3 reads a json3 caption document such as YouTube's fmt=json3
+// output with default ParseOptions.
+func ParseJSON3(r io.Reader) ([]TranscriptSegment, error) {
+	return parseJSON3Transcript(r, ParseOptions{})
+}
+
+// parseJSON3Transcript returns one segment per json3 event with text. For
+// automatic tracks the segment's Confidence is the mean word confidence.
+func parseJSON3Transcript(body io.Reader, opts ParseOptions) ([]TranscriptSegment, error) {
+	var transcript json3Transcript
+	if err := json.NewDecoder(body).Decode(&transcript); err != nil {
+		return nil, errors.Wrap(err, "failed to decode json3 transcript")
+	}
+
+	segments := make([]TranscriptSegment, 0, len(transcript.Events))
+	var text strings.Builder
+	for _, event := range transcript.Events {
+		text.Reset()
+		confSum, confCount := 0, 0
+		for _, seg := range event.Segs {
+			text.WriteString(seg.Text)
+			if seg.Conf != nil {
+				confSum += *seg.Conf
+				confCount++
+			}
+		}
+
+		cleaned := cleanCaptionText(text.String(), opts)
+		if cleaned == "" {
+			continue
+		}
+		segment := TranscriptSegment{
+			Text:      cleaned,
+			StartTime: float64(event.StartMs) / 1000,
+			Duration:  float64(event.DurationMs) / 1000,
+		}
+		if confCount > 0 {
+			mean := float64(confSum) / float64(confCount) / maxASRConfidence
+			segment.Confidence = math.Round(math.Min(mean, 1)*1000) / 1000
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
@@ -0,0 +1,148 @@
+package youtube
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SharedRateLimiter paces outbound requests of every Client that has not been
+// given its own limiter. It is unlimited until configured.
+var SharedRateLimiter = NewRateLimiter(0, 0)
+
+// RateLimiter spaces outbound requests per host so that long running batches
+// do not trip YouTube's abuse detection.
+type RateLimiter struct {
+	mu      sync.Mutex
+	spacing time.Duration
+	next    map[string]time.Time
+	waiting map[string]int
+}
+
+// HostQueue describes requests to a host waiting for the limiter
+type HostQueue struct {
+	Host    string        `json:"host"`
+	Waiting int           `json:"waiting"`
+	Wait    time.Duration `json:"-"`
+	// WaitMs estimates how long a new request would wait
+	WaitMs int64 `json:"estimatedWaitMs"`
+}
+
+// NewRateLimiter creates a limiter allowing at most requestsPerMinute requests
+// per host with at least minDelay between two of them. Zero values disable the
+// respective limit.
+func NewRateLimiter(requestsPerMinute int, minDelay time.Duration) *RateLimiter {
+	l := &RateLimiter{next: make(map[string]time.Time), waiting: make(map[string]int)}
+	l.Configure(requestsPerMinute, minDelay)
+	return l
+}
+
+// Configure replaces the limits of the limiter.
+func (l *RateLimiter) Configure(requestsPerMinute int, minDelay time.Duration) {
+	spacing := minDelay
+	if requestsPerMinute > 0 {
+		spacing = max(spacing, time.Minute/time.Duration(requestsPerMinute))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.spacing = spacing
+}
+
+// Wait blocks until a request to host may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	if l.spacing <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	slot := now
+	if next, ok := l.next[host]; ok && next.After(now) {
+		slot = next
+	}
+	l.next[host] = slot.Add(l.spacing)
+	delay := slot.Sub(now)
+	if delay > 0 {
+		l.waiting[host]++
+		defer func() {
+			l.mu.Lock()
+			l.waiting[host]--
+			l.mu.Unlock()
+		}()
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Estimate reports how many requests to host are waiting and how long a new
+// one would wait.
+func (l *RateLimiter) Estimate(host string) (waiting int, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if next, ok := l.next[host]; ok {
+		wait = max(time.Until(next), 0)
+	}
+	return l.waiting[host], wait
+}
+
+// Queues reports the waiting requests and expected delay per host, sorted by
+// host. Hosts without waiting requests or delay are omitted.
+func (l *RateLimiter) Queues() []HostQueue {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var queues []HostQueue
+	for host, next := range l.next {
+		wait := max(next.Sub(now), 0)
+		if wait == 0 && l.waiting[host] == 0 {
+			continue
+		}
+		queues = append(queues, HostQueue{
+			Host:    host,
+			Waiting: l.waiting[host],
+			Wait:    wait,
+			WaitMs:  wait.Milliseconds(),
+		})
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Host < queues[j].Host })
+	return queues
+}
+
+// WithRateLimiter makes the client use limiter instead of SharedRateLimiter.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// limitedTransport waits for the client's limiter before each request.
+type limitedTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if limiter := t.client.limiter; limiter != nil {
+		if err := limiter.Wait(req.Context(), req.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,234 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	// OAuthScope grants access to the captions of the authorizing account's
+	// videos, including private and unlisted ones
+	OAuthScope = "https://www.googleapis.com/auth/youtube.force-ssl"
+	// tokenExpiryDelta refreshes access tokens shortly before they expire
+	tokenExpiryDelta = time.Minute
+)
+
+// ErrNotAuthorized is returned by the Data API source before a YouTube
+// account has been authorized
+var ErrNotAuthorized = errors.New("youtube account not authorized")
+
+// OAuthToken is the token of the authorized account, stored as JSON
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// OAuth runs Google's authorization code flow for a deployment and keeps the
+// token of the account that authorized it, refreshing it as needed.
+type OAuth struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	tokenFile    string
+	httpClient   *http.Client
+
+	mu    sync.Mutex
+	token *OAuthToken
+}
+
+// NewOAuth creates the flow for a Google OAuth client. When tokenFile is set
+// the token is loaded from and saved to it, otherwise it is kept in memory
+// only and the account has to be authorized again after a restart.
+func NewOAuth(clientID, clientSecret, redirectURL, tokenFile string) (*OAuth, error) {
+	if clientID == "" || clientSecret == "" || redirectURL == "" {
+		return nil, errors.New("oauth client id, secret and redirect url are required")
+	}
+
+	o := &OAuth{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		tokenFile:    tokenFile,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if tokenFile == "" {
+		return o, nil
+	}
+
+	data, err := os.ReadFile(tokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read oauth token")
+	}
+	var token OAuthToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, errors.Wrap(err, "failed to decode oauth token")
+	}
+	o.token = &token
+	return o, nil
+}
+
+// AuthCodeURL returns the Google consent page URL the account owner visits.
+// state is passed back to the redirect URL unchanged.
+func (o *OAuth) AuthCodeURL(state string) string {
+	q := url.Values{}
+	q.Set("client_id", o.clientID)
+	q.Set("redirect_uri", o.redirectURL)
+	q.Set("response_type", "code")
+	q.Set("scope", OAuthScope)
+	q.Set("state", state)
+	// A refresh token is only issued with offline access and, for accounts
+	// that authorized before, a fresh consent
+	q.Set("access_type", "offline")
+	q.Set("prompt", "consent")
+	return googleAuthURL + "?" + q.Encode()
+}
+
+// Exchange trades the code received at the redirect URL for a token.
+func (o *OAuth) Exchange(ctx context.Context, code string) error {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.redirectURL)
+
+	token, err := o.requestToken(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.setToken(token)
+}
+
+// Authorized reports whether an account has been authorized
+func (o *OAuth) Authorized() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.token != nil
+}
+
+// AccessToken returns a valid access token, refreshing it when it is about
+// to expire.
+func (o *OAuth) AccessToken(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == nil {
+		return "", ErrNotAuthorized
+	}
+	if time.Until(o.token.Expiry) > tokenExpiryDelta {
+		return o.token.AccessToken, nil
+	}
+	if o.token.RefreshToken == "" {
+		return "", errors.Wrap(ErrNotAuthorized, "access token expired")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", o.token.RefreshToken)
+	token, err := o.requestToken(ctx, form)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to refresh access token")
+	}
+	// Refresh responses usually omit the refresh token
+	if token.RefreshToken == "" {
+		token.RefreshToken = o.token.RefreshToken
+	}
+	if err := o.setToken(token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (o *OAuth) requestToken(ctx context.Context, form url.Values) (*OAuthToken, error) {
+	form.Set("client_id", o.clientID)
+	form.Set("client_secret", o.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to perform request")
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "failed to decode token response")
+	}
+	if body.Error != "" {
+		return nil, errors.Errorf("token request failed: %s: %s", body.Error, body.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return &OAuthToken{
+		AccessToken:  body.AccessToken,
+		RefreshToken: body.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// setToken keeps token and saves it to the token file. The caller holds mu.
+func (o *OAuth) setToken(token *OAuthToken) error {
+	o.token = token
+	if o.tokenFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode oauth token")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(o.tokenFile), ".token-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to save oauth token")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to save oauth token")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to save oauth token")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), o.tokenFile), "failed to save oauth token")
+}
+
+// WithOAuth downloads captions through the Data API with the account
+// authorized via o, which also covers its private and unlisted videos. The
+// "dataapi" source is tried last unless the source order names it, and only
+// for requests WithAccount.
+func WithOAuth(o *OAuth) Option {
+	return func(c *Client) {
+		c.oauth = o
+	}
+}
@@ -0,0 +1,133 @@
+package youtube
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// POTokenProvider supplies the proof of origin (PO) tokens YouTube requires
+// on the caption URLs of some videos. Tokens are minted by BotGuard in a
+// browser, which this package does not run, so they come from outside.
+type POTokenProvider interface {
+	// Session returns the visitor data of the session player requests are
+	// made in, "" to let YouTube start a new one. Providers handing out
+	// tokens minted elsewhere name the session they were minted for.
+	Session() string
+	// POToken returns a token for the captions of videoID, requested in the
+	// session identified by visitorData
+	POToken(ctx context.Context, videoID, visitorData string) (string, error)
+}
+
+// StaticPOToken is a PO token used for every video, bound to the visitor
+// session VisitorData rather than to a video. Player requests are made in that
+// session so that the token validates against the caption URLs they return.
+type StaticPOToken struct {
+	Token       string
+	VisitorData string
+}
+
+func (t StaticPOToken) Session() string {
+	return t.VisitorData
+}
+
+func (t StaticPOToken) POToken(_ context.Context, _ string, visitorData string) (string, error) {
+	if t.VisitorData != "" && visitorData != "" && visitorData != t.VisitorData {
+		return "", errors.New("PO token was minted for another visitor session")
+	}
+	return t.Token, nil
+}
+
+// WithPOTokenProvider attaches PO tokens from provider to the caption URLs
+// that require one. Without it those tracks are requested as is, which
+// YouTube usually answers with 403.
+func WithPOTokenProvider(provider POTokenProvider) Option {
+	return func(c *Client) {
+		c.poTokens = provider
+	}
+}
+
+// visitorData returns the visitor session to make player requests in, "" for
+// a new one.
+func (c *Client) visitorData() string {
+	if c.poTokens == nil {
+		return ""
+	}
+	return c.poTokens.Session()
+}
+
+// requiresPOToken reports whether YouTube marked a caption URL of the player
+// response as requiring a PO token.
+func requiresPOToken(baseURL string) bool {
+	_, rawQuery, _ := strings.Cut(baseURL, "?")
+	query, _ := url.ParseQuery(rawQuery)
+	return query.Get("exp") == "xpe" && query.Get("pot") == ""
+}
+
+// captionURL returns the URL of track in format, "" for the track's default,
+// with a PO token attached when the track requires one.
+func (c *Client) captionURL(ctx context.Context, track CaptionTrack, format string) (string, error) {
+	params := url.Values{}
+	if format != "" {
+		params.Set("fmt", format)
+	}
+	if requiresPOToken(track.BaseURL) && c.poTokens != nil {
+		token, err := c.poTokens.POToken(ctx, track.VideoID, track.visitorData)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get PO token")
+		}
+		if token != "" {
+			params.Set("pot", token)
+			params.Set("potc", "1")
+			if !hasParam(track.BaseURL, "c") {
+				params.Set("c", innerTubeClientName)
+			}
+		}
+	}
+	return setParams(track.BaseURL, params), nil
+}
+
+// poTokenError tells a 403 for captionURL caused by a missing PO token apart
+// from being blocked.
+func poTokenError(captionURL string, err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden && requiresPOToken(captionURL) {
+		return errors.Wrap(err, "caption track requires a PO token")
+	}
+	return err
+}
+
+// setParams replaces the parameters of rawURL named in params and appends
+// them, keeping every other parameter verbatim. Caption URLs are signed, so
+// their parameters must not be reordered or re-encoded.
+func setParams(rawURL string, params url.Values) string {
+	base, rawQuery, _ := strings.Cut(rawURL, "?")
+	var kept []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && params.Has(name) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if encoded := params.Encode(); encoded != "" {
+		kept = append(kept, encoded)
+	}
+	if len(kept) == 0 {
+		return base
+	}
+	return base + "?" + strings.Join(kept, "&")
+}
+
+// hasParam reports whether rawURL has the query parameter name.
+func hasParam(rawURL, name string) bool {
+	_, rawQuery, _ := strings.Cut(rawURL, "?")
+	query, _ := url.ParseQuery(rawQuery)
+	return query.Has(name)
+}
@@ -0,0 +1,179 @@
+package youtube
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrTrackNotFound is returned when no source offers the requested language
+	ErrTrackNotFound = errors.New("caption track not found")
+	// ErrUnsupportedFormat is returned for unknown raw caption formats
+	ErrUnsupportedFormat = errors.New("unsupported caption format")
+)
+
+// rawContentTypes maps the caption formats YouTube serves via the fmt
+// parameter to their content types.
+var rawContentTypes = map[string]string{
+	"ttml":  "application/ttml+xml; charset=utf-8",
+	"srv3":  "text/xml; charset=utf-8",
+	"json3": "application/json; charset=utf-8",
+}
+
+// RawCaptionFormats returns the formats accepted by GetRawCaptions
+func RawCaptionFormats() []string {
+	formats := make([]string, 0, len(rawContentTypes))
+	for format := range rawContentTypes {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// RawCaptions is an unmodified caption payload as served by YouTube
+type RawCaptions struct {
+	VideoID      string
+	LanguageCode string
+	Format       string
+	ContentType  string
+	Data         []byte
+}
+
+// RawCaptionSource is implemented by caption sources that can download a
+// track in one of YouTube's native formats without parsing it.
+type RawCaptionSource interface {
+	CaptionSource
+	FetchRaw(ctx context.Context, track CaptionTrack, format string) ([]byte, error)
+}
+
+// GetRawCaptions downloads the caption track in lang unchanged, in format
+// ttml, srv3 or json3. Manually created tracks are preferred over automatic
+// ones. Payloads are cached briefly.
+func (c *Client) GetRawCaptions(ctx context.Context, videoID, lang, format string, opts ...RequestOption) (*RawCaptions, error) {
+	contentType, ok := rawContentTypes[format]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnsupportedFormat, "format %q", format)
+	}
+	reqOpts := newRequestOptions(opts)
+
+	key := reqOpts.cacheKey(videoID) + "|" + lang + "|" + format
+	if cached, ok := c.rawCache.get(key); ok {
+		c.logger.Debug("Raw caption cache hit", "video_id", videoID, "language", lang, "format", format)
+		return cached, nil
+	}
+
+	var lastErr error
+	for _, source := range c.sources {
+		rawSource, ok := source.(RawCaptionSource)
+		if !ok || !c.sourceAllowed(source, reqOpts) {
+			continue
+		}
+
+		tracks, err := rawSource.ListTracks(ctx, videoID, reqOpts)
+		if err != nil {
+			c.logger.Warn("Failed to list caption tracks", "source", source.Name(), "video_id", videoID, "error", err)
+			lastErr = err
+			continue
+		}
+		track, ok := trackForLanguage(tracks, lang)
+		if !ok {
+			continue
+		}
+
+		data, err := rawSource.FetchRaw(ctx, track, format)
+		if err != nil {
+			c.logger.Warn("Failed to fetch raw captions", "source", source.Name(), "video_id", videoID, "error", err)
+			lastErr = err
+			continue
+		}
+
+		raw := &RawCaptions{
+			VideoID:      videoID,
+			LanguageCode: track.LanguageCode,
+			Format:       format,
+			ContentType:  contentType,
+			Data:         data,
+		}
+		// What only the authorized account can see is not shared
+		if _, private := source.(*dataAPISource); !private || c.dataAPI {
+			c.rawCache.put(key, raw)
+		}
+		return raw, nil
+	}
+
+	if lastErr != nil {
+		return nil, errors.Wrap(lastErr, "no caption track available")
+	}
+	return nil, ErrTrackNotFound
+}
+
+// ListCaptionTracks lists the caption tracks of the first source that lists
+// any, without downloading a track. The innertube source only needs the
+// player response, which is cached.
+func (c *Client) ListCaptionTracks(ctx context.Context, videoID string, opts ...RequestOption) ([]CaptionTrack, error) {
+	reqOpts := newRequestOptions(opts)
+
+	var lastErr error
+	for _, source := range c.sources {
+		if !c.sourceAllowed(source, reqOpts) {
+			continue
+		}
+		tracks, err := source.ListTracks(ctx, videoID, reqOpts)
+		if err != nil {
+			c.logger.Warn("Failed to list caption tracks", "source", source.Name(), "video_id", videoID, "error", err)
+			lastErr = err
+			continue
+		}
+		if len(tracks) > 0 {
+			return tracks, nil
+		}
+	}
+
+	if lastErr != nil {
+		return nil, errors.Wrap(lastErr, "failed to list caption tracks")
+	}
+	return nil, nil
+}
+
+// trackForLanguage finds the track in lang, preferring manual over automatic
+// captions.
+func trackForLanguage(tracks []CaptionTrack, lang string) (CaptionTrack, bool) {
+	var fallback *CaptionTrack
+	for i, track := range tracks {
+		if track.LanguageCode != lang {
+			continue
+		}
+		if track.Kind != "asr" {
+			return track, true
+		}
+		if fallback == nil {
+			fallback = &tracks[i]
+		}
+	}
+	if fallback == nil {
+		return CaptionTrack{}, false
+	}
+	return *fallback, true
+}
+
+func (s *innerTubeSource) FetchRaw(ctx context.Context, track CaptionTrack, format string) ([]byte, error) {
+	trackURL, err := s.client.captionURL(ctx, track, format)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.client.getBody(ctx, trackURL)
+	if err != nil {
+		return nil, poTokenError(trackURL, err)
+	}
+	return body, nil
+}
+
+func (s *timedTextSource) FetchRaw(ctx context.Context, track CaptionTrack, format string) ([]byte, error) {
+	trackURL, err := s.client.captionURL(ctx, track, format)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.getBody(ctx, trackURL)
+}
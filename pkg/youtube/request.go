@@ -0,0 +1,81 @@
+package youtube
+
+// defaultUILanguage is the InnerTube hl parameter used when none is requested
+const defaultUILanguage = "en"
+
+// RequestOptions override client defaults for a single request
+type RequestOptions struct {
+	// Region is the InnerTube gl parameter, e.g. "US". It controls region
+	// locked caption availability.
+	Region string
+	// UILanguage is the InnerTube hl parameter, e.g. "de". It controls the
+	// language of localized titles and track names.
+	UILanguage string
+	// Language selects the caption track language. Empty prefers English and
+	// falls back to the first track.
+	Language string
+	// Confidence downloads automatic tracks as json3, which carries the per
+	// word confidence reported in TranscriptSegment.Confidence
+	Confidence bool
+	// Account lets the dataapi source download captions as the account
+	// authorized with WithOAuth, see WithAccount
+	Account bool
+}
+
+// RequestOption sets a per request override
+type RequestOption func(*RequestOptions)
+
+// WithRegion sets the InnerTube region (gl) of a request
+func WithRegion(region string) RequestOption {
+	return func(o *RequestOptions) {
+		o.Region = region
+	}
+}
+
+// WithUILanguage sets the InnerTube interface language (hl) of a request
+func WithUILanguage(lang string) RequestOption {
+	return func(o *RequestOptions) {
+		o.UILanguage = lang
+	}
+}
+
+// WithLanguage requests the caption track in lang, e.g. "tr"
+func WithLanguage(lang string) RequestOption {
+	return func(o *RequestOptions) {
+		o.Language = lang
+	}
+}
+
+// WithConfidence requests speech recognition confidence for automatic
+// tracks
+func WithConfidence() RequestOption {
+	return func(o *RequestOptions) {
+		o.Confidence = true
+	}
+}
+
+// WithAccount lets the request fall back to the dataapi source of a client
+// with WithOAuth. That source sees the authorized account's private and
+// unlisted videos, so only requests made by the account's owner should use
+// it, and their results must not be shared with other callers.
+func WithAccount() RequestOption {
+	return func(o *RequestOptions) {
+		o.Account = true
+	}
+}
+
+func newRequestOptions(opts []RequestOption) RequestOptions {
+	o := RequestOptions{UILanguage: defaultUILanguage}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.UILanguage == "" {
+		o.UILanguage = defaultUILanguage
+	}
+	return o
+}
+
+// cacheKey identifies a player response for a video and request options
+func (o RequestOptions) cacheKey(videoID string) string {
+	return videoID + "|" + o.UILanguage + "|" + o.Region
+}
@@ -0,0 +1,198 @@
+package youtube
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CaptionTrack describes a caption track offered by a CaptionSource
+type CaptionTrack struct {
+	// Source is the name of the CaptionSource that listed the track
+	Source       string `json:"source"`
+	VideoID      string `json:"videoId"`
+	LanguageCode string `json:"languageCode"`
+	Name         string `json:"name,omitempty"`
+	// Kind is "asr" for automatically generated tracks
+	Kind  string `json:"kind,omitempty"`
+	VssID string `json:"vssId,omitempty"`
+	// BaseURL is the source specific location of the track payload
+	BaseURL string `json:"-"`
+	// visitorData is the session of the player response listing the track
+	visitorData string
+	// confidence requests the track in a format carrying confidence
+	confidence bool
+}
+
+// CaptionSource lists and downloads caption tracks for a video. The client
+// tries its sources in order until one of them returns a transcript.
+type CaptionSource interface {
+	Name() string
+	ListTracks(ctx context.Context, videoID string, opts RequestOptions) ([]CaptionTrack, error)
+	FetchTrack(ctx context.Context, track CaptionTrack) ([]TranscriptSegment, error)
+}
+
+// DetailsSource is implemented by caption sources that also know the title
+// and channel of a video, sparing a player request to YouTube when they
+// provide the transcript.
+type DetailsSource interface {
+	CaptionSource
+	VideoDetails(ctx context.Context, videoID string) (title, channel string, err error)
+}
+
+// SourceFactory creates a caption source bound to a client
+type SourceFactory func(c *Client) CaptionSource
+
+// DefaultSourceOrder is the resolution order used when none is configured
+var DefaultSourceOrder = []string{"innertube", "timedtext"}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]SourceFactory{
+		"innertube": func(c *Client) CaptionSource { return &innerTubeSource{client: c} },
+		"timedtext": func(c *Client) CaptionSource { return &timedTextSource{client: c} },
+		"dataapi":   func(c *Client) CaptionSource { return &dataAPISource{client: c} },
+	}
+)
+
+// RegisterSource makes a caption source available under name so that it can
+// be referenced by WithSourceOrder. It panics if name is already registered.
+func RegisterSource(name string, factory SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	if factory == nil {
+		panic("youtube: RegisterSource factory is nil")
+	}
+	if _, dup := sources[name]; dup {
+		panic("youtube: RegisterSource called twice for source " + name)
+	}
+	sources[name] = factory
+}
+
+// RegisteredSources returns the sorted names of all registered sources
+func RegisteredSources() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithSourceOrder replaces the client's caption sources with the registered
+// sources named, tried in the given order. Unknown names are logged and
+// skipped.
+func WithSourceOrder(names ...string) Option {
+	return func(c *Client) {
+		c.sources = c.resolveSources(names)
+	}
+}
+
+// WithFallbackSources appends caption sources that are tried after the
+// configured ones.
+func WithFallbackSources(sources ...CaptionSource) Option {
+	return func(c *Client) {
+		c.sources = append(c.sources, sources...)
+	}
+}
+
+func (c *Client) resolveSources(names []string) []CaptionSource {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	resolved := make([]CaptionSource, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		factory, ok := sources[name]
+		if !ok {
+			c.logger.Warn("Ignoring unknown caption source", "source", name)
+			continue
+		}
+		resolved = append(resolved, factory(c))
+	}
+	return resolved
+}
+
+// SourceNames returns the names of the caption sources in the order they are
+// tried
+func (c *Client) SourceNames() []string {
+	names := make([]string, 0, len(c.sources))
+	for _, source := range c.sources {
+		names = append(names, source.Name())
+	}
+	return names
+}
+
+// HTTPClient returns the client's rate limited HTTP client for use by custom
+// caption sources.
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// innerTubeSource lists tracks from the InnerTube player response and
+// downloads them as TTML
+type innerTubeSource struct {
+	client *Client
+}
+
+func (s *innerTubeSource) Name() string {
+	return "innertube"
+}
+
+func (s *innerTubeSource) ListTracks(ctx context.Context, videoID string, opts RequestOptions) ([]CaptionTrack, error) {
+	playerResp, err := s.client.getPlayerResponse(ctx, videoID, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get player response")
+	}
+
+	captionTracks := playerResp.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks
+	tracks := make([]CaptionTrack, 0, len(captionTracks))
+	for _, track := range captionTracks {
+		tracks = append(tracks, CaptionTrack{
+			Source:       s.Name(),
+			VideoID:      videoID,
+			LanguageCode: track.LanguageCode,
+			Name:         track.Name.SimpleText,
+			Kind:         track.Kind,
+			VssID:        track.VssID,
+			BaseURL:      track.BaseURL,
+			visitorData:  playerResp.ResponseContext.VisitorData,
+			confidence:   opts.Confidence,
+		})
+	}
+	return tracks, nil
+}
+
+// FetchTrack downloads tracks as TTML. Automatic tracks are downloaded as
+// json3, which carries per word confidence, when confidence was requested or
+// FeatureJSON3 is on.
+func (s *innerTubeSource) FetchTrack(ctx context.Context, track CaptionTrack) ([]TranscriptSegment, error) {
+	json3 := track.Kind == "asr" && (track.confidence || s.client.feature(FeatureJSON3, false))
+	format := "ttml"
+	if json3 {
+		format = "json3"
+	}
+	trackURL, err := s.client.captionURL(ctx, track, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []TranscriptSegment
+	if json3 {
+		segments, err = s.client.fetchJSON3(ctx, trackURL)
+	} else {
+		segments, err = s.client.fetchTTML(ctx, trackURL)
+	}
+	if err != nil {
+		return nil, poTokenError(trackURL, err)
+	}
+	return segments, nil
+}
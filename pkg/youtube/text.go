@@ -0,0 +1,94 @@
+package youtube
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ParseOptions controls how caption text is normalised while parsing
+type ParseOptions struct {
+	// PreserveStyling keeps <i>, <b> and <u> markup, including styling
+	// derived from TTML span attributes, instead of stripping it
+	PreserveStyling bool
+	// PreserveLineBreaks keeps line breaks within a cue, such as TTML <br/>
+	// elements, as newlines instead of joining the lines with spaces
+	PreserveLineBreaks bool
+}
+
+// WithParseOptions sets how the client normalises caption text
+func WithParseOptions(opts ParseOptions) Option {
+	return func(c *Client) {
+		c.parseOpts = opts
+	}
+}
+
+var (
+	tagPattern = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+	// escapedEntityPattern matches an entity whose ampersand was escaped
+	// again, such as "&amp;#39;" or "&amp;lt;"
+	escapedEntityPattern = regexp.MustCompile(`&amp;(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+	hspacePattern        = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// styleTags are the inline tags kept when styling is preserved
+var styleTags = map[string]bool{
+	"<i>": true, "</i>": true,
+	"<b>": true, "</b>": true,
+	"<u>": true, "</u>": true,
+}
+
+// cleanCaptionText strips inline markup unless styling is preserved and
+// decodes HTML entities, which YouTube frequently escapes twice. Markup is
+// stripped first, so that escaped angle brackets such as "&lt;3" stay text.
+func cleanCaptionText(text string, opts ParseOptions) string {
+	text = tagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		if opts.PreserveStyling && styleTags[strings.ToLower(tag)] {
+			return tag
+		}
+		return ""
+	})
+
+	// Undo the second level of escaping only for entities, e.g.
+	// "&amp;#39;" -> "'", so that an escaped ampersand stays one: "&amp;" -> "&"
+	if strings.ContainsRune(text, '&') {
+		text = html.UnescapeString(escapedEntityPattern.ReplaceAllString(text, "&$1;"))
+	}
+
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSpace(hspacePattern.ReplaceAllString(line, " "))
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+
+	sep := " "
+	if opts.PreserveLineBreaks {
+		sep = "\n"
+	}
+	return strings.Join(kept, sep)
+}
+
+// ttmlStyleTags returns the markup to open and close for a TTML span's style
+// attributes.
+func ttmlStyleTags(attrs []string) (open, close string) {
+	var tags []string
+	for _, value := range attrs {
+		switch value {
+		case "italic":
+			tags = append(tags, "i")
+		case "bold":
+			tags = append(tags, "b")
+		case "underline":
+			tags = append(tags, "u")
+		}
+	}
+
+	for i, tag := range tags {
+		open += "<" + tag + ">"
+		close += "</" + tags[len(tags)-1-i] + ">"
+	}
+	return open, close
+}
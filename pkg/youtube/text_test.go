@@ -0,0 +1,35 @@
+package youtube
+
+import "testing"
+
+func TestCleanCaptionText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts ParseOptions
+		want string
+	}{
+		{"plain", "hello world", ParseOptions{}, "hello world"},
+		{"single escaped", "we&#39;re here", ParseOptions{}, "we're here"},
+		{"double escaped", "we&amp;#39;re here", ParseOptions{}, "we're here"},
+		{"escaped ampersand", "rock &amp; roll", ParseOptions{}, "rock & roll"},
+		{"double escaped ampersand", "AT&amp;amp;T", ParseOptions{}, "AT&T"},
+		{"escaped angle bracket", "I &lt;3 you", ParseOptions{}, "I <3 you"},
+		{"double escaped angle bracket", "I &amp;lt;3 you", ParseOptions{}, "I <3 you"},
+		{"escaped markup stays text", "&lt;b&gt;bold&lt;/b&gt;", ParseOptions{}, "<b>bold</b>"},
+		{"markup stripped", `<font color="#E5E5E5">grey</font> text`, ParseOptions{}, "grey text"},
+		{"styling kept", "<i>so</i> <b>bold</b>", ParseOptions{PreserveStyling: true}, "<i>so</i> <b>bold</b>"},
+		{"styling stripped", "<i>so</i> <b>bold</b>", ParseOptions{}, "so bold"},
+		{"comparison", "1 < 2 and 3 > 2", ParseOptions{}, "1 < 2 and 3 > 2"},
+		{"nbsp", "a&nbsp;&nbsp;b", ParseOptions{}, "a b"},
+		{"lines joined", "first\nsecond", ParseOptions{}, "first second"},
+		{"lines kept", "first \n\n second", ParseOptions{PreserveLineBreaks: true}, "first\nsecond"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanCaptionText(tt.in, tt.opts); got != tt.want {
+				t.Errorf("cleanCaptionText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
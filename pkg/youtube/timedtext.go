@@ -0,0 +1,114 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const timedTextEndpoint = "https://video.google.com/timedtext"
+
+// timedTextSource uses the legacy timedtext endpoint, which still serves
+// tracks for some videos whose player response lists none.
+type timedTextSource struct {
+	client *Client
+}
+
+func (s *timedTextSource) Name() string {
+	return "timedtext"
+}
+
+type timedTextList struct {
+	Tracks []struct {
+		Name         string `xml:"name,attr"`
+		LanguageCode string `xml:"lang_code,attr"`
+		Kind         string `xml:"kind,attr"`
+	} `xml:"track"`
+}
+
+func (s *timedTextSource) ListTracks(ctx context.Context, videoID string, _ RequestOptions) ([]CaptionTrack, error) {
+	q := url.Values{}
+	q.Set("type", "list")
+	q.Set("v", videoID)
+
+	body, err := s.client.getBody(ctx, timedTextEndpoint+"?"+q.Encode())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch timedtext track list")
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var list timedTextList
+	if err := xml.Unmarshal(body, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode timedtext track list")
+	}
+
+	tracks := make([]CaptionTrack, 0, len(list.Tracks))
+	for _, t := range list.Tracks {
+		q := url.Values{}
+		q.Set("v", videoID)
+		q.Set("lang", t.LanguageCode)
+		if t.Name != "" {
+			q.Set("name", t.Name)
+		}
+		if t.Kind != "" {
+			q.Set("kind", t.Kind)
+		}
+
+		tracks = append(tracks, CaptionTrack{
+			Source:       s.Name(),
+			VideoID:      videoID,
+			LanguageCode: t.LanguageCode,
+			Name:         t.Name,
+			Kind:         t.Kind,
+			BaseURL:      timedTextEndpoint + "?" + q.Encode(),
+		})
+	}
+	return tracks, nil
+}
+
+type timedTextTranscript struct {
+	Texts []struct {
+		Start string `xml:"start,attr"`
+		Dur   string `xml:"dur,attr"`
+		Text  string `xml:",chardata"`
+	} `xml:"text"`
+}
+
+func (s *timedTextSource) FetchTrack(ctx context.Context, track CaptionTrack) ([]TranscriptSegment, error) {
+	body, err := s.client.getBody(ctx, track.BaseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch timedtext track")
+	}
+
+	var transcript timedTextTranscript
+	if err := xml.Unmarshal(body, &transcript); err != nil {
+		return nil, errors.Wrap(err, "failed to decode timedtext track")
+	}
+
+	segments := make([]TranscriptSegment, 0, len(transcript.Texts))
+	for _, t := range transcript.Texts {
+		start, err := strconv.ParseFloat(t.Start, 64)
+		if err != nil {
+			continue
+		}
+		dur, _ := strconv.ParseFloat(t.Dur, 64)
+
+		// timedtext escapes entities twice, the XML decoder removes one level
+		text := cleanCaptionText(t.Text, s.client.parseOpts)
+		if text == "" {
+			continue
+		}
+		segments = append(segments, TranscriptSegment{
+			Text:      text,
+			StartTime: start,
+			Duration:  dur,
+		})
+	}
+	return segments, nil
+}
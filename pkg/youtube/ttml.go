@@ -0,0 +1,193 @@
+package youtube
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// textBufferPool recycles the buffers used to accumulate cue text while
+// streaming TTML documents. Unlike strings.Builder, a bytes.Buffer keeps its
+// capacity across Reset, so a pooled buffer is reused for every cue.
+var textBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// ParseTTML reads a TTML caption document such as YouTube's fmt=ttml output
+// with default ParseOptions.
+func ParseTTML(r io.Reader) ([]TranscriptSegment, error) {
+	return parseTTMLTranscript(r, 0, ParseOptions{})
+}
+
+// parseTTMLTranscript streams a TTML document and returns one segment per
+// <p> cue. sizeHint is the expected number of cues and is used to pre-size the
+// result.
+func parseTTMLTranscript(body io.Reader, sizeHint int, opts ParseOptions) ([]TranscriptSegment, error) {
+	decoder := xml.NewDecoder(body)
+	// Captions contain HTML entities such as &nbsp; that are not defined in XML
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	segments := make([]TranscriptSegment, 0, sizeHint)
+
+	text := textBufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		text.Reset()
+		textBufferPool.Put(text)
+	}()
+
+	var begin, end string
+	var closers []string // closing style markup of the open elements in a <p>
+	sawRoot := false
+	depth := 0 // nesting depth inside the current <p>, 0 when outside of one
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode TTML XML")
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 && t.Name.Local == "br" {
+				if opts.PreserveLineBreaks {
+					text.WriteString("\n")
+				} else {
+					text.WriteString(" ")
+				}
+			}
+			if !sawRoot {
+				if t.Name.Local != "tt" {
+					// Usually an HTML consent or error page served instead
+					rootErr := &xml.SyntaxError{Msg: fmt.Sprintf("unexpected root element <%s>", t.Name.Local), Line: 1}
+					return nil, errors.Wrap(rootErr, "failed to decode TTML XML")
+				}
+				sawRoot = true
+				continue
+			}
+			if depth > 0 {
+				depth++
+				open, close := "", ""
+				if opts.PreserveStyling {
+					open, close = ttmlStyleTags([]string{
+						xmlAttr(t, "fontStyle"), xmlAttr(t, "fontWeight"), xmlAttr(t, "textDecoration"),
+					})
+				}
+				text.WriteString(open)
+				closers = append(closers, close)
+				continue
+			}
+			if t.Name.Local == "p" {
+				depth = 1
+				begin, end = xmlAttr(t, "begin"), xmlAttr(t, "end")
+				closers = closers[:0]
+				text.Reset()
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 && len(closers) > 0 {
+				text.WriteString(closers[len(closers)-1])
+				closers = closers[:len(closers)-1]
+			}
+			if depth == 0 {
+				if segment, ok := newTTMLSegment(begin, end, text.String(), opts); ok {
+					segments = append(segments, segment)
+				}
+			}
+		case xml.CharData:
+			if depth > 0 {
+				// Source formatting whitespace is not significant in TTML,
+				// only <br/> marks a line break
+				for _, r := range string(t) {
+					if r == '\n' || r == '\r' {
+						r = ' '
+					}
+					text.WriteRune(r)
+				}
+			}
+		}
+	}
+
+	if !sawRoot {
+		return nil, errors.Wrap(io.ErrUnexpectedEOF, "failed to decode TTML XML")
+	}
+	return segments, nil
+}
+
+// newTTMLSegment builds a segment from a cue's timing attributes and text.
+// It reports false for cues with unparsable timings or no text.
+func newTTMLSegment(begin, end, text string, opts ParseOptions) (TranscriptSegment, bool) {
+	startTime, err := parseTime(begin)
+	if err != nil {
+		slog.Warn("Failed to parse begin time", "time", begin, "error", err)
+		return TranscriptSegment{}, false
+	}
+	endTime, err := parseTime(end)
+	if err != nil {
+		slog.Warn("Failed to parse end time", "time", end, "error", err)
+		return TranscriptSegment{}, false
+	}
+	if !validCueTimes(startTime, endTime) {
+		slog.Warn("Skipping cue with invalid times", "begin", begin, "end", end)
+		return TranscriptSegment{}, false
+	}
+
+	segment := TranscriptSegment{
+		Text:      cleanCaptionText(text, opts),
+		StartTime: startTime,
+		Duration:  endTime - startTime,
+	}
+	return segment, segment.Text != ""
+}
+
+// validCueTimes reports whether a cue's times are finite, not negative and in
+// order, so that its segment can be encoded as JSON.
+func validCueTimes(start, end float64) bool {
+	return !math.IsNaN(start) && !math.IsInf(start, 0) && !math.IsNaN(end) && !math.IsInf(end, 0) &&
+		start >= 0 && end >= start
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func parseTime(timeStr string) (float64, error) {
+	if strings.HasSuffix(timeStr, "s") {
+		timeStr = strings.TrimSuffix(timeStr, "s")
+		return strconv.ParseFloat(timeStr, 64)
+	}
+	parts := strings.Split(timeStr, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time format: %s", timeStr)
+	}
+	hours, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, err
+	}
+	return hours*3600 + minutes*60 + seconds, nil
+}
@@ -0,0 +1,102 @@
+package youtube
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestParseTTMLLineBreaks parses testdata/multiline.ttml, a hand-written
+// document in the shape of YouTube's fmt=ttml responses with multi-line,
+// nested and double escaped cues.
+func TestParseTTMLLineBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ParseOptions
+		want []string
+	}{
+		{
+			name: "joined",
+			opts: ParseOptions{},
+			want: []string{
+				"we're no strangers to love you know the rules and so do I",
+				"a full commitment's what I'm thinking of",
+				"[Music]",
+				`rock & roll never dies tell me "why"`,
+				"onetwo three",
+			},
+		},
+		{
+			name: "preserved",
+			opts: ParseOptions{PreserveLineBreaks: true},
+			want: []string{
+				"we're no strangers to love\nyou know the rules and so do I",
+				"a full commitment's\nwhat I'm thinking of",
+				"[Music]",
+				"rock & roll never dies\ntell me \"why\"",
+				"onetwo\nthree",
+			},
+		},
+		{
+			name: "preserved with styling",
+			opts: ParseOptions{PreserveLineBreaks: true, PreserveStyling: true},
+			want: []string{
+				"we're no strangers to love\nyou know the rules and so do I",
+				"<i>a full commitment's</i>\nwhat I'm <b>thinking <u>of</u></b>",
+				"[Music]",
+				"rock & roll never dies\ntell me \"why\"",
+				"onetwo\nthree",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open("testdata/multiline.ttml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			segments, err := parseTTMLTranscript(f, 0, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(segments) != len(tt.want) {
+				t.Fatalf("got %d segments, want %d", len(segments), len(tt.want))
+			}
+			for i, segment := range segments {
+				if segment.Text != tt.want[i] {
+					t.Errorf("segment %d = %q, want %q", i, segment.Text, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// ttmlDocument returns a TTML document of n cues in YouTube's fmt=ttml shape.
+func ttmlDocument(n int) []byte {
+	var doc bytes.Buffer
+	doc.WriteString(`<?xml version="1.0" encoding="utf-8" ?><tt xml:lang="en" xmlns="http://www.w3.org/ns/ttml"><body region="r1"><div>`)
+	for i := range n {
+		fmt.Fprintf(&doc, `<p begin="%d.000s" end="%d.500s" style="s2">cue number %d with <span style="s3">some styled</span> words &amp; an entity<br/>and a second line</p>`, i, i, i)
+	}
+	doc.WriteString(`</div></body></tt>`)
+	return doc.Bytes()
+}
+
+func BenchmarkParseTTML(b *testing.B) {
+	doc := ttmlDocument(10_000)
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	for range b.N {
+		segments, err := parseTTMLTranscript(bytes.NewReader(doc), 10_000, ParseOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(segments) != 10_000 {
+			b.Fatalf("got %d segments, want 10000", len(segments))
+		}
+	}
+}
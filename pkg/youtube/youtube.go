@@ -5,14 +5,12 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"encoding/xml"
-	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,13 +19,37 @@ import (
 
 // Client represents the YouTube API client
 type Client struct {
-	httpClient *http.Client
-	apiKey     string
-	logger     *slog.Logger
+	httpClient  *http.Client
+	apiKey      string
+	logger      *slog.Logger
+	playerCache *playerCache
+	rawCache    *lruCache[*RawCaptions]
+	limiter     *RateLimiter
+	sources     []CaptionSource
+	parseOpts   ParseOptions
+	oauth       *OAuth
+	dataAPI     bool
+	features    Features
+	poTokens    POTokenProvider
+}
+
+// Option configures optional Client behaviour
+type Option func(*Client)
+
+// WithPlayerCache sets the TTL and maximum number of entries of the player
+// response cache. A zero ttl or size disables the cache.
+func WithPlayerCache(ttl time.Duration, size int) Option {
+	return func(c *Client) {
+		if ttl <= 0 || size <= 0 {
+			c.playerCache = nil
+			return
+		}
+		c.playerCache = newPlayerCache(ttl, size)
+	}
 }
 
 // NewClient creates a new YouTube client
-func NewClient(apiKey string, insecureSkipVerify bool, logger *slog.Logger) *Client {
+func NewClient(apiKey string, insecureSkipVerify bool, logger *slog.Logger, opts ...Option) *Client {
 	if logger == nil {
 		logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 	}
@@ -49,11 +71,33 @@ func NewClient(apiKey string, insecureSkipVerify bool, logger *slog.Logger) *Cli
 		}
 	}
 
-	return &Client{
-		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: httpTransport},
-		apiKey:     apiKey,
-		logger:     logger,
+	c := &Client{
+		apiKey:      apiKey,
+		logger:      logger,
+		playerCache: newPlayerCache(defaultPlayerCacheTTL, defaultPlayerCacheSize),
+		rawCache:    newLRUCache[*RawCaptions](defaultRawCacheTTL, defaultRawCacheSize),
+		limiter:     SharedRateLimiter,
+	}
+	c.httpClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &limitedTransport{base: httpTransport, client: c},
+	}
+	c.sources = c.resolveSources(DefaultSourceOrder)
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.dataAPI {
+		c.sources = c.resolveSources([]string{"dataapi"})
+	} else if c.oauth != nil && !slices.ContainsFunc(c.sources, func(s CaptionSource) bool { return s.Name() == "dataapi" }) {
+		c.sources = append(c.sources, c.resolveSources([]string{"dataapi"})...)
 	}
+
+	return c
+}
+
+// RateLimiter returns the limiter pacing the client's requests, nil if none
+func (c *Client) RateLimiter() *RateLimiter {
+	return c.limiter
 }
 
 // Logger returns the client's logger
@@ -66,6 +110,9 @@ type TranscriptSegment struct {
 	Text      string  `json:"text"`
 	StartTime float64 `json:"start"`
 	Duration  float64 `json:"duration"`
+	// Confidence is the speech recognition confidence between 0 and 1, when
+	// the automatic track provides one
+	Confidence float64 `json:"confidence,omitempty"`
 }
 
 // Transcript represents the full transcript
@@ -73,153 +120,256 @@ type Transcript struct {
 	Segments []TranscriptSegment `json:"segments"`
 }
 
-// TranscriptResponse combines raw and formatted transcripts
+// TranscriptResponse combines raw and formatted transcripts. Formatting is
+// done by the format package.
 type TranscriptResponse struct {
-	Title     string      `json:"title"`
+	Title string `json:"title"`
+	// Channel is the name of the uploading channel
+	Channel string `json:"channel,omitempty"`
+	// Language is the language code of the caption track
+	Language string `json:"language,omitempty"`
+	// Source is the name of the CaptionSource the transcript came from
+	Source    string      `json:"source,omitempty"`
 	Raw       *Transcript `json:"raw"`
 	Formatted []string    `json:"formatted"`
+	// Chapters are the creator's chapters from the video description
+	Chapters []Chapter `json:"chapters,omitempty"`
 }
 
-// GetTranscript fetches the raw transcript and title from YouTube
-func (c *Client) GetTranscript(ctx context.Context, videoID string) (*TranscriptResponse, error) {
-	playerResp, err := c.getPlayerResponse(ctx, videoID)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get player response")
-	}
+// GetTranscript fetches the raw transcript and title from YouTube. Caption
+// sources are tried in order until one of them yields a transcript.
+func (c *Client) GetTranscript(ctx context.Context, videoID string, opts ...RequestOption) (*TranscriptResponse, error) {
+	reqOpts := newRequestOptions(opts)
 
-	// Extract video title from player response
-	title := playerResp.VideoDetails.Title
-	if title == "" {
-		c.logger.Warn("No title found in player response")
+	var lastErr error
+	for _, source := range c.sources {
+		if !c.sourceAllowed(source, reqOpts) {
+			continue
+		}
+		tracks, err := source.ListTracks(ctx, videoID, reqOpts)
+		if err != nil {
+			c.logger.Warn("Failed to list caption tracks", "source", source.Name(), "video_id", videoID, "error", err)
+			lastErr = err
+			continue
+		}
+		c.logger.Info("Found caption tracks", "source", source.Name(), "count", len(tracks))
+		if len(tracks) == 0 {
+			continue
+		}
+
+		track := preferredTrack(tracks)
+		if reqOpts.Language != "" {
+			var ok bool
+			if track, ok = trackForLanguage(tracks, reqOpts.Language); !ok {
+				c.logger.Info("No caption track in requested language", "source", source.Name(), "language", reqOpts.Language)
+				continue
+			}
+		}
+		c.logger.Debug("Selected caption track", "source", source.Name(), "language", track.LanguageCode, "vss_id", track.VssID)
+
+		segments, err := source.FetchTrack(ctx, track)
+		if err != nil {
+			c.logger.Warn("Failed to fetch caption track", "source", source.Name(), "video_id", videoID, "error", err)
+			lastErr = err
+			continue
+		}
+		c.logger.Info("Parsed segments", "source", source.Name(), "count", len(segments))
+
+		title, channel := c.sourceVideoDetails(ctx, source, videoID, reqOpts)
+		resp := &TranscriptResponse{
+			Title:    title,
+			Channel:  channel,
+			Language: track.LanguageCode,
+			Source:   source.Name(),
+			Raw:      &Transcript{Segments: segments},
+		}
+		// Sources with their own details and the Data API backend avoid
+		// the InnerTube player request
+		if _, ok := source.(DetailsSource); !ok && !c.dataAPI {
+			resp.Chapters = c.videoChapters(ctx, videoID, reqOpts)
+		}
+		return resp, nil
 	}
 
-	captionTracks := c.extractCaptionTracks(playerResp)
-	c.logger.Info("Found caption tracks", "count", len(captionTracks))
-	if len(captionTracks) == 0 {
-		return nil, errors.New("no caption tracks available")
+	if lastErr != nil {
+		return nil, errors.Wrap(lastErr, "no caption tracks available")
+	}
+	if reqOpts.Language != "" {
+		return nil, errors.Wrapf(ErrTrackNotFound, "language %q", reqOpts.Language)
 	}
+	return nil, ErrNoCaptions
+}
 
-	var captionURL string
-	for _, track := range captionTracks {
-		c.logger.Debug("Caption track details", "VssID", track.VssID, "LanguageCode", track.LanguageCode, "URL", track.BaseURL)
-		if strings.HasPrefix(track.VssID, ".en") || track.LanguageCode == "en" {
-			captionURL = track.BaseURL
-			break
+// sourceVideoDetails asks source for the title and channel name if it knows
+// them, falling back to videoDetails.
+func (c *Client) sourceVideoDetails(ctx context.Context, source CaptionSource, videoID string, opts RequestOptions) (title, channel string) {
+	if detailsSource, ok := source.(DetailsSource); ok {
+		title, channel, err := detailsSource.VideoDetails(ctx, videoID)
+		if err == nil && title != "" {
+			return title, channel
 		}
+		c.logger.Warn("Failed to get video details from source", "source", source.Name(), "video_id", videoID, "error", err)
 	}
-	if captionURL == "" {
-		captionURL = captionTracks[0].BaseURL
-		c.logger.Debug("No English captions found, using default", "url", captionURL)
+	return c.videoDetails(ctx, videoID, opts)
+}
+
+// videoDetails returns the title and channel name from the player response,
+// or empty strings when they cannot be determined.
+func (c *Client) videoDetails(ctx context.Context, videoID string, opts RequestOptions) (title, channel string) {
+	if c.dataAPI {
+		return c.dataAPIVideoDetails(ctx, videoID)
 	}
 
-	ttmlURL := fmt.Sprintf("%s&fmt=ttml", captionURL)
-	resp, err := c.httpClient.Get(ttmlURL)
+	playerResp, err := c.getPlayerResponse(ctx, videoID, opts)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to fetch transcript")
+		c.logger.Warn("Failed to get player response for title", "video_id", videoID, "error", err)
+		return "", ""
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("unexpected status code: %d", resp.StatusCode)
+	title = playerResp.VideoDetails.Title
+	if title == "" {
+		c.logger.Warn("No title found in player response")
 	}
+	return title, playerResp.VideoDetails.Author
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// preferredTrack picks the first English track, falling back to the first
+// track offered.
+func preferredTrack(tracks []CaptionTrack) CaptionTrack {
+	for _, track := range tracks {
+		if strings.HasPrefix(track.VssID, ".en") || track.LanguageCode == "en" {
+			return track
+		}
+	}
+	return tracks[0]
+}
+
+// fetchTTML downloads a TTML caption document and parses it into segments.
+func (c *Client) fetchTTML(ctx context.Context, ttmlURL string) ([]TranscriptSegment, error) {
+	bodyBytes, err := c.getBody(ctx, ttmlURL)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to read TTML response")
+		return nil, errors.Wrap(err, "failed to fetch transcript")
 	}
 	c.logger.Debug("TTML response", "length", len(bodyBytes), "snippet", string(bodyBytes[:min(500, len(bodyBytes))]))
 
-	segments, err := parseTTMLTranscript(bytes.NewReader(bodyBytes))
+	cueCount := bytes.Count(bodyBytes, []byte("<p "))
+	segments, err := parseTTMLTranscript(bytes.NewReader(bodyBytes), cueCount, c.parseOpts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse TTML transcript")
 	}
-	c.logger.Info("Parsed segments", "count", len(segments))
-
-	return &TranscriptResponse{
-		Title: title,
-		Raw:   &Transcript{Segments: segments},
-	}, nil
+	return segments, nil
 }
 
-// GetFormattedTranscript fetches and formats the transcript with title
-func (c *Client) GetFormattedTranscript(ctx context.Context, videoID string, intervalSeconds float64) (*TranscriptResponse, error) {
-	transcriptResp, err := c.GetTranscript(ctx, videoID)
+// fetchJSON3 downloads a json3 caption document and parses it into segments.
+func (c *Client) fetchJSON3(ctx context.Context, json3URL string) ([]TranscriptSegment, error) {
+	bodyBytes, err := c.getBody(ctx, json3URL)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "failed to fetch transcript")
 	}
-	formatted, err := c.FormatTranscript(ctx, transcriptResp.Raw, intervalSeconds)
+
+	segments, err := parseJSON3Transcript(bytes.NewReader(bodyBytes), c.parseOpts)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "failed to parse json3 transcript")
 	}
-	transcriptResp.Formatted = formatted
-	return transcriptResp, nil
+	return segments, nil
 }
 
-// FormatTranscript generates a formatted transcript from an existing raw transcript
-func (c *Client) FormatTranscript(ctx context.Context, transcript *Transcript, intervalSeconds float64) ([]string, error) {
-	if transcript == nil || len(transcript.Segments) == 0 {
-		c.logger.Warn("No segments found in transcript")
-		return nil, nil
-	}
+// getBody performs a GET request and returns the body of a 200 response.
+func (c *Client) getBody(ctx context.Context, rawURL string) ([]byte, error) {
+	return c.getBodyWithHeader(ctx, rawURL, nil)
+}
 
-	var formatted []string
-	currentStart := transcript.Segments[0].StartTime
-	var groupText strings.Builder
+// apiKeyHeader carries the API key of Google API requests. Unlike the key
+// query parameter, it does not end up in the URL quoted by transport errors.
+const apiKeyHeader = "X-Goog-Api-Key"
 
-	for _, segment := range transcript.Segments {
-		if segment.StartTime-currentStart >= intervalSeconds && groupText.Len() > 0 {
-			formatted = append(formatted, formatTimeText(currentStart, groupText.String()))
-			currentStart = segment.StartTime
-			groupText.Reset()
-		}
-		if groupText.Len() > 0 {
-			groupText.WriteString(" ")
-		}
-		groupText.WriteString(segment.Text)
+// getBodyWithHeader is getBody with additional request headers.
+func (c *Client) getBodyWithHeader(ctx context.Context, rawURL string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
 	}
-
-	if groupText.Len() > 0 {
-		formatted = append(formatted, formatTimeText(currentStart, groupText.String()))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to perform request")
 	}
+	defer resp.Body.Close()
 
-	c.logger.Info("Formatted transcript", "groups", len(formatted))
-	return formatted, nil
-}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
 
-func formatTimeText(startTime float64, text string) string {
-	hours := int(startTime / 3600)
-	minutes := int((startTime - float64(hours*3600)) / 60)
-	seconds := int(startTime - float64(hours*3600+minutes*60))
-	if hours > 0 {
-		return fmt.Sprintf("(%02d:%02d:%02d) %s", hours, minutes, seconds, text)
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
 	}
-	return fmt.Sprintf("(%02d:%02d) %s", minutes, seconds, text)
+	return bodyBytes, nil
 }
 
+// innerTubeClientName is the client player requests are made as
+const innerTubeClientName = "WEB"
+
 type playerResponse struct {
+	ResponseContext struct {
+		// VisitorData identifies the session PO tokens are bound to
+		VisitorData string `json:"visitorData"`
+	} `json:"responseContext"`
 	Captions struct {
 		PlayerCaptionsTracklistRenderer struct {
 			CaptionTracks []struct {
-				BaseURL      string `json:"baseUrl"`
+				BaseURL string `json:"baseUrl"`
+				Name    struct {
+					SimpleText string `json:"simpleText"`
+				} `json:"name"`
 				VssID        string `json:"vssId"`
 				LanguageCode string `json:"languageCode"`
+				Kind         string `json:"kind"`
 			} `json:"captionTracks"`
 		} `json:"playerCaptionsTracklistRenderer"`
 	} `json:"captions"`
 	VideoDetails struct {
-		Title string `json:"title"`
+		Title            string `json:"title"`
+		Author           string `json:"author"`
+		ShortDescription string `json:"shortDescription"`
 	} `json:"videoDetails"`
 }
 
-func (c *Client) getPlayerResponse(ctx context.Context, videoID string) (*playerResponse, error) {
+func (c *Client) getPlayerResponse(ctx context.Context, videoID string, opts RequestOptions) (*playerResponse, error) {
+	key := opts.cacheKey(videoID)
+	if cached, ok := c.playerCache.get(key); ok {
+		c.logger.Debug("Player response cache hit", "video_id", videoID)
+		return cached, nil
+	}
+
+	playerResp, err := c.fetchPlayerResponse(ctx, videoID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.playerCache.put(key, playerResp)
+	return playerResp, nil
+}
+
+func (c *Client) fetchPlayerResponse(ctx context.Context, videoID string, opts RequestOptions) (*playerResponse, error) {
 	endpoint := "https://www.youtube.com/youtubei/v1/player"
+	clientCtx := map[string]interface{}{
+		"clientName":    innerTubeClientName,
+		"clientVersion": "2.20241126.01.00",
+		"hl":            opts.UILanguage,
+	}
+	if opts.Region != "" {
+		clientCtx["gl"] = opts.Region
+	}
+	visitorData := c.visitorData()
+	if visitorData != "" {
+		clientCtx["visitorData"] = visitorData
+	}
 	data := map[string]interface{}{
 		"context": map[string]interface{}{
-			"client": map[string]interface{}{
-				"clientName":    "WEB",
-				"clientVersion": "2.20241126.01.00",
-				"hl":            "en",
-			},
+			"client": clientCtx,
 		},
 		"videoId": videoID,
 	}
@@ -235,10 +385,11 @@ func (c *Client) getPlayerResponse(ctx context.Context, videoID string) (*player
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if visitorData != "" {
+		req.Header.Set("X-Goog-Visitor-Id", visitorData)
+	}
 	if c.apiKey != "" {
-		q := req.URL.Query()
-		q.Add("key", c.apiKey)
-		req.URL.RawQuery = q.Encode()
+		req.Header.Set(apiKeyHeader, c.apiKey)
 	}
 
 	resp, err := c.httpClient.Do(req)
@@ -248,7 +399,7 @@ func (c *Client) getPlayerResponse(ctx context.Context, videoID string) (*player
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	var playerResp playerResponse
@@ -259,82 +410,6 @@ func (c *Client) getPlayerResponse(ctx context.Context, videoID string) (*player
 	return &playerResp, nil
 }
 
-func (c *Client) extractCaptionTracks(resp *playerResponse) []struct {
-	BaseURL      string `json:"baseUrl"`
-	VssID        string `json:"vssId"`
-	LanguageCode string `json:"languageCode"`
-} {
-	return resp.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks
-}
-
-type ttmlTranscript struct {
-	XMLName xml.Name `xml:"tt"`
-	Body    struct {
-		Div struct {
-			Paragraphs []struct {
-				Begin string `xml:"begin,attr"`
-				End   string `xml:"end,attr"`
-				Text  string `xml:",chardata"`
-			} `xml:"p"`
-		} `xml:"div"`
-	} `xml:"body"`
-}
-
-func parseTTMLTranscript(body io.Reader) ([]TranscriptSegment, error) {
-	var ttml ttmlTranscript
-	decoder := xml.NewDecoder(body)
-	if err := decoder.Decode(&ttml); err != nil {
-		return nil, errors.Wrap(err, "failed to decode TTML XML")
-	}
-
-	segments := make([]TranscriptSegment, 0, len(ttml.Body.Div.Paragraphs))
-	for _, p := range ttml.Body.Div.Paragraphs {
-		startTime, err := parseTime(p.Begin)
-		if err != nil {
-			slog.Warn("Failed to parse begin time", "time", p.Begin, "error", err)
-			continue
-		}
-		endTime, err := parseTime(p.End)
-		if err != nil {
-			slog.Warn("Failed to parse end time", "time", p.End, "error", err)
-			continue
-		}
-		segment := TranscriptSegment{
-			Text:      strings.TrimSpace(p.Text),
-			StartTime: startTime,
-			Duration:  endTime - startTime,
-		}
-		if segment.Text != "" {
-			segments = append(segments, segment)
-		}
-	}
-	return segments, nil
-}
-
-func parseTime(timeStr string) (float64, error) {
-	if strings.HasSuffix(timeStr, "s") {
-		timeStr = strings.TrimSuffix(timeStr, "s")
-		return strconv.ParseFloat(timeStr, 64)
-	}
-	parts := strings.Split(timeStr, ":")
-	if len(parts) != 3 {
-		return 0, fmt.Errorf("invalid time format: %s", timeStr)
-	}
-	hours, err := strconv.ParseFloat(parts[0], 64)
-	if err != nil {
-		return 0, err
-	}
-	minutes, err := strconv.ParseFloat(parts[1], 64)
-	if err != nil {
-		return 0, err
-	}
-	seconds, err := strconv.ParseFloat(parts[2], 64)
-	if err != nil {
-		return 0, err
-	}
-	return hours*3600 + minutes*60 + seconds, nil
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -45,11 +45,57 @@ function formatTime(seconds: number): string {
   return `${minutes.toString().padStart(2, '0')}:${remainingSeconds.toString().padStart(2, '0')}`;
 }
 
-const API_URL = import.meta.env.VITE_API_URL || '/api/v1';
+const API_URL = import.meta.env.VITE_API_URL || './api/v1';
+
+interface Challenge {
+  challenge: string;
+  difficulty: number;
+  header: string;
+}
+
+function leadingZeroBits(hash: Uint8Array): number {
+  let bits = 0;
+  for (const byte of hash) {
+    if (byte !== 0) {
+      return bits + Math.clz32(byte) - 24;
+    }
+    bits += 8;
+  }
+  return bits;
+}
+
+// solveChallenge finds a counter whose SHA-256 of "<challenge>:<counter>"
+// has enough leading zero bits and returns the solution header
+async function solveChallenge(): Promise<Record<string, string>> {
+  const response = await fetch(`${API_URL}/challenge`);
+  if (!response.ok) {
+    throw new Error('Failed to fetch challenge');
+  }
+  const { challenge, difficulty, header } = await response.json() as Challenge;
+
+  const encoder = new TextEncoder();
+  for (let counter = 0; ; counter++) {
+    const solution = `${challenge}:${counter}`;
+    const hash = new Uint8Array(await crypto.subtle.digest('SHA-256', encoder.encode(solution)));
+    if (leadingZeroBits(hash) >= difficulty) {
+      return { [header]: solution };
+    }
+  }
+}
+
+// fetchWithChallenge retries once with a solved challenge when the server
+// requires proof of work. Each solution is accepted for a single request.
+async function fetchWithChallenge(input: string): Promise<Response> {
+  const response = await fetch(input);
+  if (response.status !== 403 || !response.headers.has('X-PoW-Challenge')) {
+    return response;
+  }
+  return fetch(input, { headers: await solveChallenge() });
+}
 
 export async function getVideoSummary(url: string): Promise<VideoSummary> {
   try {
-    const response = await fetch(`${API_URL}/transcripts?videoUrl=${encodeURIComponent(url)}`);
+    const response = await fetchWithChallenge(`${API_URL}/transcripts?videoUrl=${encodeURIComponent(url)}`);
     const data = await response.json();
     
     if (!response.ok) {
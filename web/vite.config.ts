@@ -3,5 +3,7 @@ import { svelte } from '@sveltejs/vite-plugin-svelte'
 
 // https://vitejs.dev/config/
 export default defineConfig({
+  // Relative asset URLs keep the UI working when served under a base path
+  base: './',
   plugins: [svelte()],
 })